@@ -2,11 +2,25 @@ package bitrise
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/tuist/notary/ciconfig"
+	"github.com/tuist/notary/envsubst"
+	"github.com/tuist/notary/jsonschema"
+	"github.com/tuist/notary/pipeline"
 	"gopkg.in/yaml.v3"
 )
 
+// JSONSchema returns a JSON Schema document for BitriseYML, suitable
+// for editor autocompletion and validation of bitrise.yml files.
+func JSONSchema() []byte {
+	return jsonschema.Generate(BitriseYML{})
+}
+
 type BitriseYML struct {
 	FormatVersion string                   `yaml:"format_version"`
 	DefaultStepLib string                  `yaml:"default_step_lib"`
@@ -17,11 +31,84 @@ type BitriseYML struct {
 	App           App                      `yaml:"app,omitempty"`
 	Trigger       []Trigger                `yaml:"trigger_map,omitempty"`
 	Workflows     map[string]Workflow      `yaml:"workflows"`
+	StepBundles   StepBundleSet            `yaml:"step_bundles,omitempty"`
 	Pipelines     map[string]Pipeline      `yaml:"pipelines,omitempty"`
 	Stages        map[string]Stage         `yaml:"stages,omitempty"`
 	Meta          map[string]interface{}   `yaml:"meta,omitempty"`
 }
 
+// StepBundleModel is a named, reusable list of steps a workflow can pull
+// in wholesale with a `bundle::<id>:` step entry.
+type StepBundleModel struct {
+	Envs  []Env  `yaml:"envs,omitempty"`
+	Steps []Step `yaml:"steps"`
+}
+
+// StepBundleSet is BitriseYML.StepBundles's type: a map[string]StepBundleModel
+// keyed by bundle id, with a custom UnmarshalYAML so a YAML document
+// that defines the same bundle id twice is rejected instead of silently
+// keeping the last one.
+type StepBundleSet map[string]StepBundleModel
+
+func (s *StepBundleSet) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return fmt.Errorf("step_bundles must be a mapping of bundle id to bundle definition")
+	}
+
+	result := make(StepBundleSet, len(value.Content)/2)
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		id := value.Content[i].Value
+		if _, exists := result[id]; exists {
+			return fmt.Errorf("duplicate step bundle id %q", id)
+		}
+		var bundle StepBundleModel
+		if err := value.Content[i+1].Decode(&bundle); err != nil {
+			return fmt.Errorf("step bundle %q: %w", id, err)
+		}
+		result[id] = bundle
+	}
+	*s = result
+	return nil
+}
+
+// stepBundlePrefix marks a workflow step entry as a reference to a step
+// bundle rather than a concrete step, e.g. "bundle::ui-tests:".
+const stepBundlePrefix = "bundle::"
+
+// ValidateStepBundles checks that every bundle:: step reference in
+// config's workflows and step bundles points at a bundle that exists,
+// and that no step bundle references another step bundle: nesting step
+// bundles isn't supported.
+func ValidateStepBundles(config *BitriseYML) error {
+	for name, workflow := range config.Workflows {
+		if err := validateBundleRefs(config, workflow.Steps, fmt.Sprintf("workflow %q", name), false); err != nil {
+			return err
+		}
+	}
+	for id, bundle := range config.StepBundles {
+		if err := validateBundleRefs(config, bundle.Steps, fmt.Sprintf("step bundle %q", id), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateBundleRefs(config *BitriseYML, steps []Step, context string, forbidNesting bool) error {
+	for _, step := range steps {
+		id, ok := strings.CutPrefix(step.ID, stepBundlePrefix)
+		if !ok {
+			continue
+		}
+		if forbidNesting {
+			return fmt.Errorf("%s references step bundle %q: nested step bundles are not supported", context, id)
+		}
+		if _, exists := config.StepBundles[id]; !exists {
+			return fmt.Errorf("%s references unknown step bundle %q", context, id)
+		}
+	}
+	return nil
+}
+
 type App struct {
 	Envs []Env `yaml:"envs,omitempty"`
 }
@@ -55,6 +142,23 @@ func (e *Env) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// JSONSchema describes Env's actual YAML shape for jsonschema.Generate:
+// a single-key "NAME: value" mapping, with an optional sibling "opts:"
+// map, rather than its Key/Value/Opts struct fields.
+func (Env) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"description": `An environment variable entry, e.g. "MY_VAR: value", with an optional sibling "opts:" map of step-input-style options.`,
+		"type":        "object",
+		"patternProperties": map[string]interface{}{
+			"^(?!opts$).+$": map[string]interface{}{},
+		},
+		"properties": map[string]interface{}{
+			"opts": map[string]interface{}{"type": "object"},
+		},
+		"minProperties": 1,
+	}
+}
+
 type Trigger struct {
 	Pattern    string `yaml:"-"`
 	Workflow   string `yaml:"workflow,omitempty"`
@@ -67,29 +171,51 @@ func (t *Trigger) UnmarshalYAML(value *yaml.Node) error {
 	if err := value.Decode(&triggerMap); err != nil {
 		return err
 	}
-	
-	for pattern, v := range triggerMap {
-		t.Pattern = pattern
-		if workflow, ok := v.(string); ok {
-			t.Workflow = workflow
-		} else if workflowMap, ok := v.(map[string]interface{}); ok {
-			if w, exists := workflowMap["workflow"]; exists {
-				t.Workflow = fmt.Sprintf("%v", w)
-			}
-			if p, exists := workflowMap["pipeline"]; exists {
-				t.Pipeline = fmt.Sprintf("%v", p)
-			}
-			if pr, exists := workflowMap["is_pull_request_allowed"]; exists {
-				if b, ok := pr.(bool); ok {
-					t.IsPullRequestAllowed = b
-				}
+
+	// A trigger_map entry is a flat map of sibling keys, e.g.
+	// {push_branch: main, workflow: primary}. workflow/pipeline/
+	// is_pull_request_allowed must be matched by name, not by iteration
+	// order (map order is randomized); every other key names the match
+	// type (push_branch, tag, pull_request_source_branch, ...) and its
+	// value is the glob pattern trigger_map matches against.
+	for key, v := range triggerMap {
+		switch key {
+		case "workflow":
+			t.Workflow = fmt.Sprintf("%v", v)
+		case "pipeline":
+			t.Pipeline = fmt.Sprintf("%v", v)
+		case "is_pull_request_allowed":
+			if b, ok := v.(bool); ok {
+				t.IsPullRequestAllowed = b
 			}
+		default:
+			t.Pattern = fmt.Sprintf("%v", v)
 		}
-		break
 	}
 	return nil
 }
 
+// JSONSchema describes Trigger's actual YAML shape for
+// jsonschema.Generate: a trigger_map entry is a flat map keyed by a
+// pattern (push_branch, tag, pull_request_source_branch, ...) with
+// workflow/pipeline/is_pull_request_allowed as sibling keys, rather
+// than Trigger's Pattern/Workflow/Pipeline/IsPullRequestAllowed fields.
+func (Trigger) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"description": "A trigger_map entry: one pattern key (e.g. push_branch, tag, pull_request_source_branch) naming the branch/tag pattern to match, plus the workflow or pipeline it triggers and an optional is_pull_request_allowed flag.",
+		"type":        "object",
+		"patternProperties": map[string]interface{}{
+			"^.+$": map[string]interface{}{"type": "string"},
+		},
+		"properties": map[string]interface{}{
+			"workflow":                map[string]interface{}{"type": "string"},
+			"pipeline":                map[string]interface{}{"type": "string"},
+			"is_pull_request_allowed": map[string]interface{}{"type": "boolean"},
+		},
+		"minProperties": 1,
+	}
+}
+
 type Workflow struct {
 	Title       string   `yaml:"title,omitempty"`
 	Summary     string   `yaml:"summary,omitempty"`
@@ -133,6 +259,25 @@ func (ps *PipelineStage) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// JSONSchema describes PipelineStage's actual YAML shape for
+// jsonschema.Generate: a single-key map of stage name to run options,
+// rather than its StageName/ShouldAlwaysRun/AbortOnFail struct fields.
+func (PipelineStage) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"description": `A pipeline stage reference, keyed by stage name, e.g. "unit_tests: {should_always_run: true}".`,
+		"type":        "object",
+		"minProperties": 1,
+		"maxProperties": 1,
+		"additionalProperties": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"should_always_run": map[string]interface{}{"type": "string"},
+				"abort_on_fail":     map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+}
+
 type Stage struct {
 	Title       string                `yaml:"title,omitempty"`
 	Workflows   []StageWorkflow       `yaml:"workflows"`
@@ -167,17 +312,48 @@ func (sw *StageWorkflow) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// JSONSchema describes StageWorkflow's actual YAML shape for
+// jsonschema.Generate: a bare workflow name, or a single-key map of
+// workflow name to run options, rather than its
+// WorkflowName/RunIf struct fields.
+func (StageWorkflow) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"description": "A stage's workflow reference: a bare workflow name, or a single-key map of workflow name to run options.",
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type":          "object",
+				"minProperties": 1,
+				"maxProperties": 1,
+				"additionalProperties": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"run_if": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
 type Step struct {
 	ID     string                 `yaml:"-"`
 	Config map[string]interface{} `yaml:"-"`
 }
 
 func (s *Step) UnmarshalYAML(value *yaml.Node) error {
+	// before_run/after_run reference a workflow by its bare name
+	// ("before_run: [setup]"), with no step config attached.
+	if value.Kind == yaml.ScalarNode {
+		s.ID = value.Value
+		return nil
+	}
+
 	var stepMap map[string]interface{}
 	if err := value.Decode(&stepMap); err != nil {
 		return err
 	}
-	
+
 	for id, config := range stepMap {
 		s.ID = id
 		if configMap, ok := config.(map[string]interface{}); ok {
@@ -188,16 +364,247 @@ func (s *Step) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// JSONSchema describes Step's actual YAML shape for jsonschema.Generate:
+// a bare workflow name (only valid in before_run/after_run), or a
+// single-key map of step ID to its configuration, rather than its
+// ID/Config struct fields.
+func (Step) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"description": `A step entry, keyed by step ID, e.g. "git-clone@8: {}"; the value holds step configuration (run_if, inputs, title, ...) and may be empty. before_run/after_run entries may instead be a bare workflow name.`,
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type":          "object",
+				"minProperties": 1,
+				"maxProperties": 1,
+				"additionalProperties": map[string]interface{}{
+					"type": "object",
+				},
+			},
+		},
+	}
+}
+
+// scriptStepPrefix identifies a step as the inline "script" step, whose
+// shell code lives in its `inputs: [{content: ...}]` entry rather than
+// as a top-level field.
+const scriptStepPrefix = "script@"
+
+// ToPipeline lowers config into the provider-agnostic pipeline IR,
+// treating each workflow as one Stage in registration order. Bitrise
+// models deploy/publish behavior as ordinary Steps rather than a
+// distinct publishing block, so the resulting Pipeline never populates
+// Publish; callers that need it can still inspect Stage.Steps.
+func (config *BitriseYML) ToPipeline() *pipeline.Pipeline {
+	p := &pipeline.Pipeline{Trigger: triggersToIR(config.Trigger)}
+
+	names := make([]string, 0, len(config.Workflows))
+	for name := range config.Workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	envs := envsToMap(config.App.Envs)
+	for _, name := range names {
+		workflow := config.Workflows[name]
+		p.Stages = append(p.Stages, workflow.toStage(name, envs))
+	}
+	return p
+}
+
+func (w *Workflow) toStage(name string, appEnvs map[string]string) pipeline.Stage {
+	env := mergeEnvs(appEnvs, envsToMap(w.Envs))
+	stage := pipeline.Stage{Name: name}
+	for _, step := range w.Steps {
+		stage.Steps = append(stage.Steps, step.toIR(env))
+	}
+	return stage
+}
+
+func (s *Step) toIR(env map[string]string) pipeline.Step {
+	step := pipeline.Step{ID: s.ID, Env: env}
+	if title, ok := s.Config["title"].(string); ok {
+		step.Name = title
+	}
+	if strings.HasPrefix(s.ID, scriptStepPrefix) {
+		step.Script = stepInput(s.Config, "content")
+	}
+	return step
+}
+
+// stepInput looks up key among a step's `inputs: [{key: value}, ...]`
+// entries, returning "" if the step has no inputs or none match.
+func stepInput(config map[string]interface{}, key string) string {
+	inputs, _ := config["inputs"].([]interface{})
+	for _, raw := range inputs {
+		entry, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if v, ok := entry[key].(string); ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func envsToMap(envs []Env) map[string]string {
+	if len(envs) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(envs))
+	for _, e := range envs {
+		m[e.Key] = e.Value
+	}
+	return m
+}
+
+// mergeEnvs combines app-level and workflow-level envs, with workflow
+// envs taking precedence, mirroring how Bitrise resolves env vars at
+// run time.
+func mergeEnvs(app, workflow map[string]string) map[string]string {
+	if len(app) == 0 {
+		return workflow
+	}
+	if len(workflow) == 0 {
+		return app
+	}
+	m := make(map[string]string, len(app)+len(workflow))
+	for k, v := range app {
+		m[k] = v
+	}
+	for k, v := range workflow {
+		m[k] = v
+	}
+	return m
+}
+
+// cancelStepHints are step ID substrings that, in practice, mark a
+// workflow as cancelling its own in-progress runs. bitrise.yml has no
+// native concurrency field (unlike Codemagic's cancel_previous_builds),
+// so this is the closest available signal to extract from.
+var cancelStepHints = []string{"cancel-previous-builds", "auto-cancel"}
+
+// ExtractConcurrency returns the effective pipeline.ConcurrencyPolicy
+// for every workflow in config: its Group is the workflow name, its
+// Scope is "pr" if any trigger_map entry that targets it allows pull
+// requests (branch otherwise), and CancelInProgress reflects whether
+// one of its steps looks like it cancels superseded runs.
+func ExtractConcurrency(config *BitriseYML) map[string]pipeline.ConcurrencyPolicy {
+	policies := make(map[string]pipeline.ConcurrencyPolicy, len(config.Workflows))
+	for name, workflow := range config.Workflows {
+		policies[name] = pipeline.ConcurrencyPolicy{
+			CancelInProgress: workflowCancelsInProgress(workflow),
+			Group:            name,
+			Scope:            triggerScope(config.Trigger, name),
+		}
+	}
+	return policies
+}
+
+func workflowCancelsInProgress(w Workflow) bool {
+	for _, steps := range [][]Step{w.BeforeRun, w.Steps, w.AfterRun} {
+		for _, step := range steps {
+			for _, hint := range cancelStepHints {
+				if strings.Contains(step.ID, hint) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func triggerScope(triggers []Trigger, workflow string) pipeline.ConcurrencyScope {
+	for _, t := range triggers {
+		if t.Workflow == workflow && t.IsPullRequestAllowed {
+			return pipeline.ScopePR
+		}
+	}
+	return pipeline.ScopeBranch
+}
+
+// triggersToIR folds a trigger_map's patterns and
+// is_pull_request_allowed flags into a single Trigger: every pattern
+// becomes a branch (Bitrise trigger_map patterns match branch names by
+// default), and PullRequest is set if any entry allows pull requests.
+func triggersToIR(triggers []Trigger) pipeline.Trigger {
+	t := pipeline.Trigger{}
+	for _, trigger := range triggers {
+		t.BranchInclude = append(t.BranchInclude, trigger.Pattern)
+		if trigger.IsPullRequestAllowed {
+			t.PullRequest = true
+		}
+	}
+	return t
+}
+
+// ParseBitrise reads and parses the Bitrise config at path, expanding
+// ${VAR} / $VAR references in scalar values against the OS environment.
+// Use ParseBitriseWithEnv for a custom mapping.
 func ParseBitrise(path string) (*BitriseYML, error) {
-	data, err := os.ReadFile(path)
+	return ParseBitriseWithEnv(path, os.Getenv)
+}
+
+// ParseBitriseWithEnv reads and parses the Bitrise config at path,
+// expanding ${VAR}, ${VAR:-default} and $VAR references in every scalar
+// against mapping before decoding, following the nfpm
+// ParseWithEnvMapping pattern.
+func ParseBitriseWithEnv(path string, mapping func(string) string) (*BitriseYML, error) {
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read Bitrise config file: %w", err)
 	}
+	defer f.Close()
 
-	var config BitriseYML
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	return BitriseFromReaderWithEnv(f, mapping)
+}
+
+// BitriseFromReaderWithEnv is ParseBitriseWithEnv for an already-open
+// reader: it decodes r into a YAML node tree, substitutes env
+// references in every scalar node via mapping, and only then decodes
+// the (now-substituted) tree into a BitriseYML.
+func BitriseFromReaderWithEnv(r io.Reader, mapping func(string) string) (*BitriseYML, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Bitrise config: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, fmt.Errorf("failed to parse Bitrise YAML: %w", err)
 	}
+	envsubst.Node(&root, mapping)
 
+	var config BitriseYML
+	if err := root.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse Bitrise YAML: %w", err)
+	}
 	return &config, nil
+}
+
+func parseReader(r io.Reader) (*BitriseYML, error) {
+	return BitriseFromReaderWithEnv(r, os.Getenv)
+}
+
+// ciParser implements ciconfig.Parser so the bitrise package can
+// register itself with the ciconfig registry without callers having to
+// import bitrise directly.
+type ciParser struct{}
+
+func (ciParser) Format() string { return "bitrise" }
+
+// Detect reports whether path looks like a Bitrise config by name:
+// bitrise.yml/bitrise.yaml, or any *.bitrise.yml variant.
+func (ciParser) Detect(path string) bool {
+	base := strings.ToLower(filepath.Base(path))
+	return base == "bitrise.yml" || base == "bitrise.yaml" || strings.HasSuffix(base, ".bitrise.yml") || strings.HasSuffix(base, ".bitrise.yaml")
+}
+
+func (ciParser) Parse(r io.Reader) (ciconfig.Config, error) {
+	return parseReader(r)
+}
+
+func init() {
+	ciconfig.RegisterParser("bitrise", ciParser{})
 }
\ No newline at end of file
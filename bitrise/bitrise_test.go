@@ -3,7 +3,12 @@ package bitrise
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/tuist/notary/ciconfig"
+	"github.com/tuist/notary/pipeline"
+	"gopkg.in/yaml.v3"
 )
 
 func TestParseBitrise(t *testing.T) {
@@ -236,4 +241,331 @@ func TestParseBitrise_FileNotFound(t *testing.T) {
 	if err == nil {
 		t.Error("expected error for non-existent file")
 	}
-}
\ No newline at end of file
+}
+func TestBitriseYML_ToPipeline(t *testing.T) {
+	config := &BitriseYML{
+		App: App{Envs: []Env{{Key: "BITRISE_PROJECT_PATH", Value: "MyApp.xcworkspace"}}},
+		Trigger: []Trigger{
+			{Pattern: "main", Workflow: "primary"},
+			{Pattern: "*", Workflow: "primary", IsPullRequestAllowed: true},
+		},
+		Workflows: map[string]Workflow{
+			"primary": {
+				Steps: []Step{
+					{
+						ID: "script@1",
+						Config: map[string]interface{}{
+							"title":  "Run tests",
+							"inputs": []interface{}{map[string]interface{}{"content": "echo hello"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	p := config.ToPipeline()
+
+	if len(p.Stages) != 1 || p.Stages[0].Name != "primary" {
+		t.Fatalf("expected one stage named 'primary', got %+v", p.Stages)
+	}
+	steps := p.Stages[0].Steps
+	if len(steps) != 1 || steps[0].ID != "script@1" {
+		t.Fatalf("expected one script@1 step, got %+v", steps)
+	}
+	if steps[0].Name != "Run tests" {
+		t.Errorf("expected step name 'Run tests', got %q", steps[0].Name)
+	}
+	if steps[0].Script != "echo hello" {
+		t.Errorf("expected step script 'echo hello', got %q", steps[0].Script)
+	}
+	if steps[0].Env["BITRISE_PROJECT_PATH"] != "MyApp.xcworkspace" {
+		t.Errorf("expected step env to carry app envs, got %+v", steps[0].Env)
+	}
+
+	if len(p.Trigger.BranchInclude) != 2 {
+		t.Errorf("expected 2 trigger patterns, got %v", p.Trigger.BranchInclude)
+	}
+	if !p.Trigger.PullRequest {
+		t.Error("expected trigger to allow pull requests")
+	}
+}
+
+func TestParseBitriseWithEnv(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "bitrise.yml")
+	yamlContent := `
+format_version: 13
+default_step_lib: https://github.com/bitrise-io/bitrise-steplib.git
+app:
+  envs:
+  - SCHEME: ${SCHEME:-MyApp}
+workflows:
+  primary:
+    steps:
+    - script@1:
+        title: $TITLE
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	mapping := map[string]string{"TITLE": "Run tests"}
+	config, err := ParseBitriseWithEnv(configPath, func(k string) string { return mapping[k] })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if config.App.Envs[0].Value != "MyApp" {
+		t.Errorf("expected default 'MyApp' for unset SCHEME, got %q", config.App.Envs[0].Value)
+	}
+	step := config.Workflows["primary"].Steps[0]
+	if step.Config["title"] != "Run tests" {
+		t.Errorf("expected title 'Run tests', got %v", step.Config["title"])
+	}
+}
+
+func TestExtractConcurrency(t *testing.T) {
+	config := &BitriseYML{
+		Trigger: []Trigger{
+			{Pattern: "*", Workflow: "pr", IsPullRequestAllowed: true},
+			{Pattern: "main", Workflow: "primary"},
+		},
+		Workflows: map[string]Workflow{
+			"pr": {
+				Steps: []Step{{ID: "cancel-previous-builds@1"}, {ID: "git-clone@8"}},
+			},
+			"primary": {
+				Steps: []Step{{ID: "git-clone@8"}},
+			},
+		},
+	}
+
+	policies := ExtractConcurrency(config)
+
+	pr := policies["pr"]
+	if !pr.CancelInProgress {
+		t.Error("expected 'pr' workflow to cancel in progress runs")
+	}
+	if pr.Scope != pipeline.ScopePR {
+		t.Errorf("expected 'pr' workflow scope 'pr', got %q", pr.Scope)
+	}
+	if pr.Group != "pr" {
+		t.Errorf("expected group 'pr', got %q", pr.Group)
+	}
+
+	primary := policies["primary"]
+	if primary.CancelInProgress {
+		t.Error("expected 'primary' workflow to not cancel in progress runs")
+	}
+	if primary.Scope != pipeline.ScopeBranch {
+		t.Errorf("expected 'primary' workflow scope 'branch', got %q", primary.Scope)
+	}
+}
+
+// TestExtractConcurrency_ParsedYAML guards against a regression where
+// Trigger.UnmarshalYAML only kept whichever sibling key a randomized map
+// iteration visited first, silently dropping workflow/is_pull_request_allowed
+// depending on ordering. Unlike TestExtractConcurrency and
+// TestBitriseYML_ToPipeline above, this parses real trigger_map YAML
+// instead of constructing []Trigger directly, so it actually exercises
+// the parsing path real users hit.
+func TestExtractConcurrency_ParsedYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bitrise.yml")
+	yamlContent := `
+format_version: 13
+trigger_map:
+- push_branch: main
+  workflow: primary
+- pull_request_source_branch: "*"
+  workflow: pr
+  is_pull_request_allowed: true
+workflows:
+  primary:
+    steps:
+    - git-clone@8: {}
+  pr:
+    steps:
+    - cancel-previous-builds@1: {}
+    - git-clone@8: {}
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	config, err := ParseBitrise(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policies := ExtractConcurrency(config)
+	pr := policies["pr"]
+	if !pr.CancelInProgress {
+		t.Error("expected 'pr' workflow to cancel in progress runs")
+	}
+	if pr.Scope != pipeline.ScopePR {
+		t.Errorf("expected 'pr' workflow scope 'pr', got %q", pr.Scope)
+	}
+	primary := policies["primary"]
+	if primary.Scope != pipeline.ScopeBranch {
+		t.Errorf("expected 'primary' workflow scope 'branch', got %q", primary.Scope)
+	}
+
+	p := config.ToPipeline()
+	if len(p.Trigger.BranchInclude) != 2 {
+		t.Errorf("expected 2 trigger patterns, got %v", p.Trigger.BranchInclude)
+	}
+	if p.Trigger.BranchInclude[0] != "main" {
+		t.Errorf("expected first trigger pattern 'main', got %v", p.Trigger.BranchInclude)
+	}
+	if !p.Trigger.PullRequest {
+		t.Error("expected trigger to allow pull requests")
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data := JSONSchema()
+
+	var schema map[string]interface{}
+	if err := yaml.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("generated schema is not valid JSON: %v", err)
+	}
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected properties map, got %T", schema["properties"])
+	}
+	if _, exists := properties["workflows"]; !exists {
+		t.Error("expected 'workflows' property in the generated schema")
+	}
+}
+
+func TestParseBitrise_StepBundles(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "bitrise.yml")
+	yamlContent := `
+format_version: 13
+default_step_lib: https://github.com/bitrise-io/bitrise-steplib.git
+step_bundles:
+  ui-tests:
+    envs:
+    - TEST_TYPE: ui
+    steps:
+    - script@1: {}
+workflows:
+  primary:
+    steps:
+    - bundle::ui-tests: {}
+`
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	config, err := ParseBitrise(configPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bundle, ok := config.StepBundles["ui-tests"]
+	if !ok {
+		t.Fatal("expected step bundle 'ui-tests' to exist")
+	}
+	if len(bundle.Steps) != 1 || bundle.Steps[0].ID != "script@1" {
+		t.Errorf("expected bundle to contain script@1, got %+v", bundle.Steps)
+	}
+	if len(bundle.Envs) != 1 || bundle.Envs[0].Key != "TEST_TYPE" {
+		t.Errorf("expected bundle env TEST_TYPE, got %+v", bundle.Envs)
+	}
+}
+
+func TestParseBitrise_DuplicateStepBundle(t *testing.T) {
+	var config BitriseYML
+	yamlContent := `
+step_bundles:
+  ui-tests:
+    steps:
+    - script@1: {}
+  ui-tests:
+    steps:
+    - script@2: {}
+workflows:
+  primary:
+    steps:
+    - script@1: {}
+`
+	err := yaml.Unmarshal([]byte(yamlContent), &config)
+	if err == nil {
+		t.Fatal("expected error for duplicate step bundle id")
+	}
+}
+
+func TestValidateStepBundles(t *testing.T) {
+	config := &BitriseYML{
+		StepBundles: StepBundleSet{
+			"ui-tests": StepBundleModel{Steps: []Step{{ID: "script@1"}}},
+		},
+		Workflows: map[string]Workflow{
+			"primary": {Steps: []Step{{ID: "bundle::ui-tests"}}},
+		},
+	}
+	if err := ValidateStepBundles(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	config.Workflows["primary"] = Workflow{Steps: []Step{{ID: "bundle::missing"}}}
+	if err := ValidateStepBundles(config); err == nil {
+		t.Error("expected error for unknown step bundle reference")
+	}
+
+	config.Workflows["primary"] = Workflow{Steps: []Step{{ID: "bundle::ui-tests"}}}
+	config.StepBundles["nested"] = StepBundleModel{Steps: []Step{{ID: "bundle::ui-tests"}}}
+	if err := ValidateStepBundles(config); err == nil {
+		t.Error("expected error for nested step bundle reference")
+	}
+}
+
+func TestCiParserDetect(t *testing.T) {
+	p := ciParser{}
+	if p.Format() != "bitrise" {
+		t.Errorf("expected format 'bitrise', got %q", p.Format())
+	}
+
+	cases := map[string]bool{
+		"bitrise.yml":            true,
+		"bitrise.yaml":           true,
+		"ios.bitrise.yml":        true,
+		"codemagic.yaml":         false,
+		"bitrise.yml.bak":        false,
+	}
+	for path, want := range cases {
+		if got := p.Detect(path); got != want {
+			t.Errorf("Detect(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCiParserParse(t *testing.T) {
+	p := ciParser{}
+	config, err := p.Parse(strings.NewReader("workflows:\n  primary:\n    steps: []\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.(*BitriseYML); !ok {
+		t.Errorf("expected *BitriseYML, got %T", config)
+	}
+}
+
+func TestRegisteredWithCiconfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bitrise.yml")
+	if err := os.WriteFile(path, []byte("workflows:\n  primary:\n    steps: []\n"), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	config, err := ciconfig.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.(*BitriseYML); !ok {
+		t.Errorf("expected *BitriseYML, got %T", config)
+	}
+}
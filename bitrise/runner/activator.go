@@ -0,0 +1,194 @@
+// Package runner activates Bitrise steps from a steplib and executes
+// Bitrise workflows, turning the bitrise package's parser into something
+// `notary run --format bitrise` can actually run.
+package runner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StepActivator resolves a step reference such as "script@1" against a
+// Bitrise steplib into a local checkout of that step's source, caching
+// both the steplib index and individual step sources under CacheDir.
+type StepActivator struct {
+	StepLibURL string
+	CacheDir   string
+	Offline    bool
+}
+
+// ActivatedStep is a step ready to run: its resolved local Path plus the
+// default inputs/outputs declared in its step.yml.
+type ActivatedStep struct {
+	ID      string
+	Version string
+	Path    string
+	Inputs  map[string]string
+	Outputs []string
+}
+
+type stepYML struct {
+	Title  string `yaml:"title"`
+	Source struct {
+		Git    string `yaml:"git"`
+		Commit string `yaml:"commit"`
+	} `yaml:"source"`
+	Inputs  []map[string]interface{} `yaml:"inputs"`
+	Outputs []map[string]interface{} `yaml:"outputs"`
+}
+
+// NewStepActivator builds an activator that caches into cacheDir
+// (typically ~/.notary). Offline mode fails fast instead of cloning or
+// updating anything over the network.
+func NewStepActivator(steplibURL, cacheDir string, offline bool) *StepActivator {
+	return &StepActivator{StepLibURL: steplibURL, CacheDir: cacheDir, Offline: offline}
+}
+
+// Activate resolves ref ("script@1") into a local checkout of its step
+// source, reading the definition from the steplib.
+func (a *StepActivator) Activate(ref string) (*ActivatedStep, error) {
+	id, version, err := splitRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	steplibDir, err := a.syncSteplib()
+	if err != nil {
+		return nil, err
+	}
+
+	stepYMLPath := filepath.Join(steplibDir, "steps", id, version, "step.yml")
+	data, err := os.ReadFile(stepYMLPath)
+	if err != nil {
+		return nil, fmt.Errorf("step %s not found in steplib %s: %w", ref, a.StepLibURL, err)
+	}
+
+	var def stepYML
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse step.yml for %s: %w", ref, err)
+	}
+
+	sourceDir, err := a.syncStepSource(id, version, def)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ActivatedStep{
+		ID:      id,
+		Version: version,
+		Path:    sourceDir,
+		Inputs:  flattenEntries(def.Inputs),
+		Outputs: entryKeys(def.Outputs),
+	}, nil
+}
+
+func splitRef(ref string) (id, version string, err error) {
+	parts := strings.SplitN(ref, "@", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("step reference %q must be of the form id@version", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// steplibDir returns where this activator caches a clone of StepLibURL,
+// namespaced by a hash of the URL the way go module caches do.
+func (a *StepActivator) steplibDir() string {
+	sum := sha256.Sum256([]byte(a.StepLibURL))
+	return filepath.Join(a.CacheDir, "steplib", hex.EncodeToString(sum[:])[:16])
+}
+
+func (a *StepActivator) syncSteplib() (string, error) {
+	dir := a.steplibDir()
+	_, err := os.Stat(dir)
+	switch {
+	case err == nil:
+		if a.Offline {
+			return dir, nil
+		}
+		return dir, gitRun(dir, "pull", "--ff-only")
+	case os.IsNotExist(err):
+		if a.Offline {
+			return "", fmt.Errorf("steplib cache missing at %s and offline mode is set", dir)
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			return "", fmt.Errorf("failed to create steplib cache dir: %w", err)
+		}
+		return dir, gitRun("", "clone", a.StepLibURL, dir)
+	default:
+		return "", fmt.Errorf("failed to stat steplib cache dir: %w", err)
+	}
+}
+
+func (a *StepActivator) syncStepSource(id, version string, def stepYML) (string, error) {
+	dir := filepath.Join(a.CacheDir, "steps", id, version)
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	}
+	if a.Offline {
+		return "", fmt.Errorf("step source cache missing for %s@%s and offline mode is set", id, version)
+	}
+	if def.Source.Git == "" {
+		return "", fmt.Errorf("step %s@%s has no source.git", id, version)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create step cache dir: %w", err)
+	}
+	if err := gitRun("", "clone", def.Source.Git, dir); err != nil {
+		return "", err
+	}
+
+	ref := def.Source.Commit
+	if ref == "" {
+		ref = version
+	}
+	if err := gitRun(dir, "checkout", ref); err != nil {
+		return "", fmt.Errorf("failed to checkout %s for step %s@%s: %w", ref, id, version, err)
+	}
+	return dir, nil
+}
+
+// flattenEntries turns a step.yml inputs/outputs list (each entry a
+// single-key map, with an optional sibling "opts" key) into a flat
+// key/value map of defaults.
+func flattenEntries(entries []map[string]interface{}) map[string]string {
+	out := map[string]string{}
+	for _, entry := range entries {
+		for key, value := range entry {
+			if key == "opts" || value == nil {
+				continue
+			}
+			out[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	return out
+}
+
+func entryKeys(entries []map[string]interface{}) []string {
+	var keys []string
+	for _, entry := range entries {
+		for key := range entry {
+			if key != "opts" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+func gitRun(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %v: %w\n%s", args, err, out)
+	}
+	return nil
+}
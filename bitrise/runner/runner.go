@@ -0,0 +1,348 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tuist/notary/bitrise"
+	"github.com/tuist/notary/events"
+)
+
+// Runner executes a Bitrise workflow end to end: it plans the flat,
+// bundle-expanded step list a workflow resolves to, then activates and
+// runs each planned step in order. When Emitter is set, Execute reports
+// its progress as a JSON event stream instead of printing prose.
+type Runner struct {
+	Activator *StepActivator
+	Emitter   *events.Emitter
+}
+
+// NewRunner builds a Runner that activates steps through activator.
+func NewRunner(activator *StepActivator) *Runner {
+	return &Runner{Activator: activator}
+}
+
+// PlannedStep is a single step resolved for execution: which workflow it
+// came from, which step bundle (if any) it was pulled in from, and the
+// environment it runs with once app/workflow/bundle envs are merged in
+// Bitrise's precedence order.
+type PlannedStep struct {
+	Step         bitrise.Step
+	WorkflowName string
+	BundleID     string
+	Env          map[string]string
+}
+
+// Plan is the flat, ordered list of steps RunWorkflow will execute,
+// produced by expanding before_run/after_run workflow references and
+// bundle:: step references ahead of time.
+type Plan struct {
+	Steps []PlannedStep
+}
+
+// Plan resolves workflowName from config into a flat, ordered Plan: it
+// validates step bundle references, expands before_run/after_run, and
+// expands any bundle:: step entries into their bundle's steps.
+func (r *Runner) Plan(config *bitrise.BitriseYML, workflowName string) (*Plan, error) {
+	if err := bitrise.ValidateStepBundles(config); err != nil {
+		return nil, err
+	}
+
+	order, err := expandWorkflow(config, workflowName, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	appEnv := envMap(config.App.Envs)
+
+	var plan Plan
+	for _, name := range order {
+		workflow := config.Workflows[name]
+		workflowEnv := envMap(workflow.Envs)
+		plan.Steps = append(plan.Steps, expandSteps(config, workflow.Steps, name, "", mergeEnv(appEnv, workflowEnv))...)
+	}
+	return &plan, nil
+}
+
+// expandSteps flattens steps into PlannedSteps, replacing any
+// "bundle::<id>" entry with that bundle's steps (tagged with bundleID so
+// structured logs can group them) merged with baseEnv < bundle.envs.
+func expandSteps(config *bitrise.BitriseYML, steps []bitrise.Step, workflowName, bundleID string, baseEnv map[string]string) []PlannedStep {
+	var planned []PlannedStep
+	for _, step := range steps {
+		id, ok := strings.CutPrefix(step.ID, stepBundlePrefix)
+		if !ok {
+			planned = append(planned, PlannedStep{
+				Step:         step,
+				WorkflowName: workflowName,
+				BundleID:     bundleID,
+				Env:          baseEnv,
+			})
+			continue
+		}
+
+		bundle := config.StepBundles[id]
+		bundleEnv := mergeEnv(baseEnv, envMap(bundle.Envs))
+		planned = append(planned, expandSteps(config, bundle.Steps, workflowName, id, bundleEnv)...)
+	}
+	return planned
+}
+
+// stepBundlePrefix marks a workflow step entry as a reference to a step
+// bundle rather than a concrete step, e.g. "bundle::ui-tests:".
+const stepBundlePrefix = "bundle::"
+
+// RunWorkflow plans workflowName from config and executes the resulting
+// plan.
+func (r *Runner) RunWorkflow(config *bitrise.BitriseYML, workflowName string) error {
+	plan, err := r.Plan(config, workflowName)
+	if err != nil {
+		return err
+	}
+	if r.Emitter != nil {
+		if err := r.Emitter.Emit(DescribePlan(plan)); err != nil {
+			return err
+		}
+	}
+	return r.Execute(plan)
+}
+
+// DescribePlan turns plan into a "plan" event, one JobPlan per
+// contiguous run of steps from the same workflow (before_run, the
+// workflow itself, after_run), each step tagged with the step bundle it
+// was expanded from, if any.
+func DescribePlan(plan *Plan) events.Plan {
+	var jobs []events.JobPlan
+	lastWorkflow := ""
+	for i, ps := range plan.Steps {
+		if len(jobs) == 0 || lastWorkflow != ps.WorkflowName {
+			jobs = append(jobs, events.JobPlan{Job: ps.WorkflowName})
+			lastWorkflow = ps.WorkflowName
+		}
+		last := &jobs[len(jobs)-1]
+		last.Steps = append(last.Steps, events.StepPlan{Step: i, Uses: ps.Step.ID, Bundle: ps.BundleID})
+	}
+	return events.NewPlan(jobs)
+}
+
+// Execute runs every step in plan in order. Once a step fails, later
+// steps are skipped unless they set is_always_run: true, matching
+// Bitrise's build_failed mode. If Emitter is set, progress is reported
+// as a JSON event stream instead of being printed as prose.
+func (r *Runner) Execute(plan *Plan) error {
+	var workflow string
+	var failed error
+
+	finishWorkflow := func(name string) {
+		if name == "" || r.Emitter == nil {
+			return
+		}
+		conclusion := "success"
+		if failed != nil {
+			conclusion = "failure"
+		}
+		r.Emitter.Emit(events.NewJobFinished(name, conclusion))
+	}
+
+	for i, ps := range plan.Steps {
+		if ps.WorkflowName != workflow {
+			finishWorkflow(workflow)
+			workflow = ps.WorkflowName
+			if r.Emitter != nil {
+				r.Emitter.Emit(events.NewJobStarted(workflow, nil))
+			} else {
+				fmt.Printf("\n📦 Workflow: %s\n", workflow)
+			}
+		}
+
+		alwaysRun, _ := ps.Step.Config["is_always_run"].(bool)
+		if shouldSkip(failed, alwaysRun) {
+			if r.Emitter == nil {
+				fmt.Printf("  ⏭  Skipping %s (workflow failed)\n", ps.Step.ID)
+			}
+			continue
+		}
+
+		start := time.Now()
+		err := r.runStep(ps, i)
+		if r.Emitter != nil {
+			exitCode := 0
+			if err != nil {
+				exitCode = 1
+			}
+			r.Emitter.Emit(events.NewStepFinished(exitCode, time.Since(start).Milliseconds()))
+		}
+		if err != nil {
+			err = fmt.Errorf("workflow %q: %w", workflow, err)
+			if failed == nil {
+				failed = err
+			}
+		}
+	}
+	finishWorkflow(workflow)
+	return failed
+}
+
+// shouldSkip reports whether a step should be skipped because a prior
+// step in the build has already failed: once in build_failed mode,
+// every step is skipped except ones that set is_always_run: true.
+func shouldSkip(failed error, alwaysRun bool) bool {
+	return failed != nil && !alwaysRun
+}
+
+// runStep activates and runs a planned step, merging its precomputed
+// app/workflow/bundle env with the step's inputs (its step.yml defaults,
+// then the explicit values given in the workflow) in that order of
+// precedence.
+func (r *Runner) runStep(ps PlannedStep, index int) error {
+	step := ps.Step
+	runIf, _ := step.Config["run_if"].(string)
+	ok, err := evalRunIf(runIf)
+	if err != nil {
+		return fmt.Errorf("step %q: %w", step.ID, err)
+	}
+	if !ok {
+		if r.Emitter == nil {
+			fmt.Printf("  ⏭  Skipping %s (run_if is false)\n", step.ID)
+		}
+		return nil
+	}
+
+	activated, err := r.Activator.Activate(step.ID)
+	if err != nil {
+		return err
+	}
+
+	stepEnv := mergeEnv(ps.Env, activated.Inputs, stepInputs(step))
+
+	if r.Emitter != nil {
+		r.Emitter.Emit(events.NewStepStarted(ps.WorkflowName, index, step.ID, "", ps.BundleID))
+	} else if ps.BundleID != "" {
+		fmt.Printf("  ▶ %s (bundle: %s)\n", step.ID, ps.BundleID)
+	} else {
+		fmt.Printf("  ▶ %s\n", step.ID)
+	}
+
+	stdout, stderr := io.Writer(os.Stdout), io.Writer(os.Stderr)
+	if r.Emitter != nil {
+		stdout = events.NewStreamWriter(r.Emitter, "stdout")
+		stderr = events.NewStreamWriter(r.Emitter, "stderr")
+	}
+	return runStepScript(activated.Path, stepEnv, stdout, stderr)
+}
+
+// expandWorkflow returns the flat, dependency-ordered list of workflow
+// names before_run/after_run expand name into, erroring on a reference
+// to a missing workflow or a before_run/after_run cycle.
+func expandWorkflow(config *bitrise.BitriseYML, name string, visiting map[string]bool) ([]string, error) {
+	if visiting[name] {
+		return nil, fmt.Errorf("cycle detected in before_run/after_run expansion of %q", name)
+	}
+	workflow, ok := config.Workflows[name]
+	if !ok {
+		return nil, fmt.Errorf("workflow %q not found", name)
+	}
+
+	visiting[name] = true
+	defer delete(visiting, name)
+
+	var order []string
+	for _, before := range workflow.BeforeRun {
+		expanded, err := expandWorkflow(config, before.ID, visiting)
+		if err != nil {
+			return nil, err
+		}
+		order = append(order, expanded...)
+	}
+	order = append(order, name)
+	for _, after := range workflow.AfterRun {
+		expanded, err := expandWorkflow(config, after.ID, visiting)
+		if err != nil {
+			return nil, err
+		}
+		order = append(order, expanded...)
+	}
+	return order, nil
+}
+
+func envMap(envs []bitrise.Env) map[string]string {
+	out := map[string]string{}
+	for _, e := range envs {
+		out[e.Key] = e.Value
+	}
+	return out
+}
+
+// stepInputs reads the `inputs:` list a workflow step declares inline,
+// which bitrise.Step leaves as raw YAML-decoded data in Config.
+func stepInputs(step bitrise.Step) map[string]string {
+	raw, ok := step.Config["inputs"].([]interface{})
+	if !ok {
+		return nil
+	}
+	out := map[string]string{}
+	for _, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for key, value := range entry {
+			if key == "opts" || value == nil {
+				continue
+			}
+			out[key] = fmt.Sprintf("%v", value)
+		}
+	}
+	return out
+}
+
+func mergeEnv(maps ...map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// evalRunIf renders expr, a Bitrise run_if template such as
+// `{{getenv "X" | ne ""}}`, and reports whether it evaluated to "true".
+// An empty expr always runs.
+func evalRunIf(expr string) (bool, error) {
+	if expr == "" {
+		return true, nil
+	}
+
+	tmpl, err := template.New("run_if").Funcs(template.FuncMap{"getenv": os.Getenv}).Parse(expr)
+	if err != nil {
+		return false, fmt.Errorf("invalid run_if template %q: %w", expr, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return false, fmt.Errorf("failed to evaluate run_if template %q: %w", expr, err)
+	}
+	return buf.String() == "true", nil
+}
+
+func runStepScript(path string, env map[string]string, stdout, stderr io.Writer) error {
+	script := filepath.Join(path, "step.sh")
+	cmd := exec.Command("bash", script)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Stdin = os.Stdin
+
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return cmd.Run()
+}
@@ -0,0 +1,232 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/tuist/notary/bitrise"
+)
+
+func TestSplitRef(t *testing.T) {
+	id, version, err := splitRef("script@1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != "script" || version != "1" {
+		t.Errorf("expected script/1, got %s/%s", id, version)
+	}
+
+	if _, _, err := splitRef("script"); err == nil {
+		t.Error("expected error for ref with no version")
+	}
+}
+
+func TestFlattenEntries(t *testing.T) {
+	entries := []map[string]interface{}{
+		{"content": "echo hi", "opts": map[string]interface{}{"title": "Content"}},
+		{"is_debug": true},
+	}
+	got := flattenEntries(entries)
+	if got["content"] != "echo hi" {
+		t.Errorf("expected content input to survive, got %v", got)
+	}
+	if got["is_debug"] != "true" {
+		t.Errorf("expected is_debug=true, got %v", got)
+	}
+	if _, ok := got["opts"]; ok {
+		t.Error("opts should not be treated as an input")
+	}
+}
+
+func TestEvalRunIf(t *testing.T) {
+	os.Setenv("NOTARY_TEST_RUN_IF", "set")
+	defer os.Unsetenv("NOTARY_TEST_RUN_IF")
+
+	ok, err := evalRunIf(`{{getenv "NOTARY_TEST_RUN_IF" | ne ""}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Error("expected run_if to evaluate true when env var is set")
+	}
+
+	ok, err = evalRunIf(`{{getenv "NOTARY_TEST_RUN_IF_UNSET" | ne ""}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok {
+		t.Error("expected run_if to evaluate false when env var is unset")
+	}
+
+	ok, err = evalRunIf("")
+	if err != nil || !ok {
+		t.Errorf("expected empty run_if to always run, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestMergeEnv(t *testing.T) {
+	got := mergeEnv(
+		map[string]string{"A": "app", "B": "app"},
+		map[string]string{"B": "workflow"},
+		map[string]string{"C": "default-input"},
+		map[string]string{"C": "explicit-input"},
+	)
+	if got["A"] != "app" || got["B"] != "workflow" || got["C"] != "explicit-input" {
+		t.Errorf("unexpected precedence result: %v", got)
+	}
+}
+
+func TestExpandWorkflow(t *testing.T) {
+	config := &bitrise.BitriseYML{
+		Workflows: map[string]bitrise.Workflow{
+			"setup":   {},
+			"cleanup": {},
+			"main": {
+				BeforeRun: []bitrise.Step{{ID: "setup"}},
+				AfterRun:  []bitrise.Step{{ID: "cleanup"}},
+			},
+		},
+	}
+
+	order, err := expandWorkflow(config, "main", map[string]bool{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"setup", "main", "cleanup"}
+	if len(order) != len(want) {
+		t.Fatalf("expected %v, got %v", want, order)
+	}
+	for i, name := range want {
+		if order[i] != name {
+			t.Errorf("expected %v, got %v", want, order)
+			break
+		}
+	}
+}
+
+func TestExpandWorkflowCycle(t *testing.T) {
+	config := &bitrise.BitriseYML{
+		Workflows: map[string]bitrise.Workflow{
+			"a": {BeforeRun: []bitrise.Step{{ID: "b"}}},
+			"b": {BeforeRun: []bitrise.Step{{ID: "a"}}},
+		},
+	}
+
+	if _, err := expandWorkflow(config, "a", map[string]bool{}); err == nil {
+		t.Error("expected cycle error, got none")
+	}
+}
+
+func TestStepInputs(t *testing.T) {
+	step := bitrise.Step{
+		ID: "script@1",
+		Config: map[string]interface{}{
+			"inputs": []interface{}{
+				map[string]interface{}{"content": "echo hi"},
+			},
+		},
+	}
+	got := stepInputs(step)
+	if got["content"] != "echo hi" {
+		t.Errorf("expected content input, got %v", got)
+	}
+}
+
+func TestExpandSteps_Bundle(t *testing.T) {
+	config := &bitrise.BitriseYML{
+		StepBundles: bitrise.StepBundleSet{
+			"ui-tests": bitrise.StepBundleModel{
+				Envs:  []bitrise.Env{{Key: "BUNDLE_ENV", Value: "bundle"}},
+				Steps: []bitrise.Step{{ID: "script@1"}},
+			},
+		},
+	}
+	steps := []bitrise.Step{{ID: "git-clone@8"}, {ID: "bundle::ui-tests"}}
+
+	planned := expandSteps(config, steps, "primary", "", map[string]string{"APP_ENV": "app"})
+	if len(planned) != 2 {
+		t.Fatalf("expected 2 planned steps, got %d", len(planned))
+	}
+	if planned[0].BundleID != "" || planned[0].Step.ID != "git-clone@8" {
+		t.Errorf("expected first step to be the plain step, got %+v", planned[0])
+	}
+	if planned[1].BundleID != "ui-tests" || planned[1].Step.ID != "script@1" {
+		t.Errorf("expected second step to come from the ui-tests bundle, got %+v", planned[1])
+	}
+	if planned[1].Env["APP_ENV"] != "app" || planned[1].Env["BUNDLE_ENV"] != "bundle" {
+		t.Errorf("expected bundle step env to include both app and bundle envs, got %v", planned[1].Env)
+	}
+}
+
+func TestPlan_EnvPrecedence(t *testing.T) {
+	config := &bitrise.BitriseYML{
+		App: bitrise.App{Envs: []bitrise.Env{{Key: "LEVEL", Value: "app"}}},
+		StepBundles: bitrise.StepBundleSet{
+			"bundle": bitrise.StepBundleModel{
+				Envs:  []bitrise.Env{{Key: "LEVEL", Value: "bundle"}},
+				Steps: []bitrise.Step{{ID: "script@1"}},
+			},
+		},
+		Workflows: map[string]bitrise.Workflow{
+			"primary": {
+				Envs:  []bitrise.Env{{Key: "LEVEL", Value: "workflow"}},
+				Steps: []bitrise.Step{{ID: "bundle::bundle"}},
+			},
+		},
+	}
+
+	r := NewRunner(nil)
+	plan, err := r.Plan(config, "primary")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Steps) != 1 {
+		t.Fatalf("expected 1 planned step, got %d", len(plan.Steps))
+	}
+	if got := plan.Steps[0].Env["LEVEL"]; got != "bundle" {
+		t.Errorf("expected bundle env to win over workflow/app env, got %q", got)
+	}
+}
+
+func TestPlan_UnknownBundleReference(t *testing.T) {
+	config := &bitrise.BitriseYML{
+		Workflows: map[string]bitrise.Workflow{
+			"primary": {Steps: []bitrise.Step{{ID: "bundle::missing"}}},
+		},
+	}
+	r := NewRunner(nil)
+	if _, err := r.Plan(config, "primary"); err == nil {
+		t.Error("expected error for unknown step bundle reference")
+	}
+}
+
+func TestShouldSkip(t *testing.T) {
+	if shouldSkip(nil, false) {
+		t.Error("expected no skip when nothing has failed yet")
+	}
+	if !shouldSkip(fmt.Errorf("boom"), false) {
+		t.Error("expected a regular step to be skipped once the build has failed")
+	}
+	if shouldSkip(fmt.Errorf("boom"), true) {
+		t.Error("expected an is_always_run step to still run once the build has failed")
+	}
+}
+
+func TestDescribePlan(t *testing.T) {
+	plan := &Plan{Steps: []PlannedStep{
+		{Step: bitrise.Step{ID: "git-clone@8"}, WorkflowName: "primary"},
+		{Step: bitrise.Step{ID: "script@1"}, WorkflowName: "primary", BundleID: "ui-tests"},
+	}}
+
+	described := DescribePlan(plan)
+	if described.Type != "plan" {
+		t.Fatalf("expected type plan, got %q", described.Type)
+	}
+	if len(described.Jobs) != 1 || described.Jobs[0].Job != "primary" {
+		t.Fatalf("expected a single primary job, got %+v", described.Jobs)
+	}
+	if len(described.Jobs[0].Steps) != 2 || described.Jobs[0].Steps[1].Bundle != "ui-tests" {
+		t.Errorf("expected second step to record its bundle, got %+v", described.Jobs[0].Steps)
+	}
+}
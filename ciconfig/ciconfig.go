@@ -0,0 +1,84 @@
+// Package ciconfig defines a format-agnostic interface for parsing CI
+// provider config files (Bitrise, Codemagic, ...) and a package-level
+// registry providers register themselves into, so callers can parse a
+// file without hardcoding which provider it belongs to. Adding a new
+// provider is a matter of implementing Parser and calling
+// RegisterParser from that provider's package init.
+package ciconfig
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// Config is the root value a Parser produces, e.g. *bitrise.BitriseYML
+// or *codemagic.CodemagicYML.
+type Config interface{}
+
+// Parser parses one CI provider's config format.
+type Parser interface {
+	// Parse decodes r into a Config.
+	Parse(r io.Reader) (Config, error)
+	// Detect reports whether path looks like this parser's format,
+	// judged from its name (and, where that's ambiguous, its content).
+	Detect(path string) bool
+	// Format is the parser's registry key, e.g. "bitrise".
+	Format() string
+}
+
+// ErrNoParser is returned by Get and ParseFile when no parser is
+// registered for a format, or none of them detect a given file.
+var ErrNoParser = errors.New("ciconfig: no parser found")
+
+var parsers = map[string]Parser{}
+
+// RegisterParser registers p under format. It panics if format is
+// already registered, since that's always a programming error (two
+// provider packages claiming the same format key).
+func RegisterParser(format string, p Parser) {
+	if _, exists := parsers[format]; exists {
+		panic(fmt.Sprintf("ciconfig: parser %q already registered", format))
+	}
+	parsers[format] = p
+}
+
+// Get returns the parser registered under format.
+func Get(format string) (Parser, error) {
+	p, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoParser, format)
+	}
+	return p, nil
+}
+
+// ParseFile detects path's format from every registered parser's
+// Detect, in registration-key order, and parses it with the first one
+// that matches.
+func ParseFile(path string) (Config, error) {
+	for _, format := range registeredFormats() {
+		p := parsers[format]
+		if !p.Detect(path) {
+			continue
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		defer f.Close()
+		return p.Parse(f)
+	}
+	return nil, fmt.Errorf("%w: could not detect a CI config format for %s", ErrNoParser, path)
+}
+
+func registeredFormats() []string {
+	formats := make([]string, 0, len(parsers))
+	for format := range parsers {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
@@ -0,0 +1,111 @@
+package ciconfig
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+type fakeParser struct {
+	format string
+	suffix string
+}
+
+func (p fakeParser) Format() string { return p.format }
+func (p fakeParser) Detect(path string) bool {
+	return strings.HasSuffix(path, p.suffix)
+}
+func (p fakeParser) Parse(r io.Reader) (Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func resetParsers() {
+	parsers = map[string]Parser{}
+}
+
+func TestRegisterAndGet(t *testing.T) {
+	defer resetParsers()
+	resetParsers()
+
+	RegisterParser("fake", fakeParser{format: "fake", suffix: ".fake"})
+
+	p, err := Get("fake")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Format() != "fake" {
+		t.Errorf("expected fake parser, got %q", p.Format())
+	}
+}
+
+func TestGetUnknownFormat(t *testing.T) {
+	defer resetParsers()
+	resetParsers()
+
+	_, err := Get("made-up")
+	if !errors.Is(err, ErrNoParser) {
+		t.Errorf("expected ErrNoParser, got %v", err)
+	}
+}
+
+func TestRegisterParserPanicsOnDuplicate(t *testing.T) {
+	defer resetParsers()
+	resetParsers()
+
+	RegisterParser("fake", fakeParser{format: "fake", suffix: ".fake"})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic when registering a duplicate format")
+		}
+	}()
+	RegisterParser("fake", fakeParser{format: "fake", suffix: ".fake"})
+}
+
+func TestParseFile(t *testing.T) {
+	defer resetParsers()
+	resetParsers()
+
+	RegisterParser("fake", fakeParser{format: "fake", suffix: ".fake"})
+
+	dir := t.TempDir()
+	path := dir + "/config.fake"
+	if err := writeFile(path, "hello"); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	config, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config != "hello" {
+		t.Errorf("expected parsed config 'hello', got %v", config)
+	}
+}
+
+func TestParseFileNoMatch(t *testing.T) {
+	defer resetParsers()
+	resetParsers()
+
+	RegisterParser("fake", fakeParser{format: "fake", suffix: ".fake"})
+
+	dir := t.TempDir()
+	path := dir + "/config.yml"
+	if err := writeFile(path, "hello"); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	if _, err := ParseFile(path); !errors.Is(err, ErrNoParser) {
+		t.Errorf("expected ErrNoParser, got %v", err)
+	}
+}
+
+func writeFile(path, content string) error {
+	return os.WriteFile(path, []byte(content), 0o644)
+}
@@ -2,11 +2,26 @@ package codemagic
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 
+	"github.com/tuist/notary/ciconfig"
+	"github.com/tuist/notary/envsubst"
+	"github.com/tuist/notary/jsonschema"
+	"github.com/tuist/notary/pipeline"
 	"gopkg.in/yaml.v3"
 )
 
+// JSONSchema returns a JSON Schema document for CodemagicYML, suitable
+// for editor autocompletion and validation of codemagic.yaml files.
+func JSONSchema() []byte {
+	return jsonschema.Generate(CodemagicYML{})
+}
+
 type CodemagicYML struct {
 	Workflows map[string]Workflow `yaml:"workflows"`
 }
@@ -115,6 +130,27 @@ func (s *Script) UnmarshalYAML(value *yaml.Node) error {
 	return nil
 }
 
+// JSONSchema describes Script's actual YAML shape for
+// jsonschema.Generate: a bare shell script string, or an object with
+// its Name/Script/IgnoreFailure fields, rather than always reflecting
+// it as an object.
+func (Script) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"description": "A workflow script: a bare shell script string, or an object with name/script/ignore_failure.",
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"name":           map[string]interface{}{"type": "string"},
+					"script":         map[string]interface{}{"type": "string"},
+					"ignore_failure": map[string]interface{}{"type": "boolean"},
+				},
+			},
+		},
+	}
+}
+
 type Publishing struct {
 	Email        *EmailPublishing        `yaml:"email,omitempty"`
 	Slack        *SlackPublishing        `yaml:"slack,omitempty"`
@@ -174,16 +210,217 @@ type NotifySettings struct {
 	Failure bool `yaml:"failure,omitempty"`
 }
 
+// ToPipeline lowers config into the provider-agnostic pipeline IR,
+// treating each Codemagic workflow as one Stage. Triggers and
+// publishing targets are unioned across all workflows, since the IR
+// models one Trigger/[]Publish per Pipeline rather than per workflow.
+func (c *CodemagicYML) ToPipeline() *pipeline.Pipeline {
+	p := &pipeline.Pipeline{}
+
+	names := make([]string, 0, len(c.Workflows))
+	for name := range c.Workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		workflow := c.Workflows[name]
+		p.Stages = append(p.Stages, workflow.toStage(name))
+		mergeTrigger(&p.Trigger, workflow.Triggering)
+		p.Publish = append(p.Publish, workflow.Publishing.toPublish()...)
+	}
+
+	return p
+}
+
+// ExtractConcurrency returns the effective pipeline.ConcurrencyPolicy
+// for every workflow in config, derived from its triggering.events and
+// triggering.cancel_previous_builds settings.
+func ExtractConcurrency(config *CodemagicYML) map[string]pipeline.ConcurrencyPolicy {
+	policies := make(map[string]pipeline.ConcurrencyPolicy, len(config.Workflows))
+	for name, workflow := range config.Workflows {
+		policies[name] = workflowConcurrency(name, workflow)
+	}
+	return policies
+}
+
+func workflowConcurrency(name string, w Workflow) pipeline.ConcurrencyPolicy {
+	policy := pipeline.ConcurrencyPolicy{Group: name, Scope: pipeline.ScopeBranch}
+	if w.Triggering == nil {
+		return policy
+	}
+
+	hasPullRequest, hasTag := false, false
+	for _, event := range w.Triggering.Events {
+		switch {
+		case event.PullRequest != nil:
+			hasPullRequest = true
+		case event.Tag != nil:
+			hasTag = true
+		}
+	}
+
+	switch {
+	case hasPullRequest:
+		policy.Scope = pipeline.ScopePR
+		policy.CancelInProgress = w.Triggering.Cancel.OnPullRequest
+	case hasTag:
+		policy.Scope = pipeline.ScopeTag
+	default:
+		policy.CancelInProgress = w.Triggering.Cancel.OnPush
+	}
+	return policy
+}
+
+func (w *Workflow) toStage(name string) pipeline.Stage {
+	stage := pipeline.Stage{Name: name}
+	for i, script := range w.Scripts {
+		step := pipeline.Step{
+			Name:          script.Name,
+			Script:        script.Script,
+			IgnoreFailure: script.IgnoreFailure,
+		}
+		if len(w.Environment.Vars) > 0 {
+			step.Env = w.Environment.Vars
+		}
+		// Artifacts are collected once per workflow, after its last
+		// script runs, so attach them to the stage's final step.
+		if i == len(w.Scripts)-1 {
+			step.Artifacts = w.Artifacts
+		}
+		stage.Steps = append(stage.Steps, step)
+	}
+	return stage
+}
+
+// mergeTrigger folds t's events and branch/tag patterns into trigger,
+// unioning across however many workflows a Codemagic config defines.
+func mergeTrigger(trigger *pipeline.Trigger, t *Triggering) {
+	if t == nil {
+		return
+	}
+	for _, event := range t.Events {
+		switch {
+		case event.Push != nil:
+			trigger.Events = append(trigger.Events, "push")
+		case event.Tag != nil:
+			trigger.Events = append(trigger.Events, "tag")
+		case event.PullRequest != nil:
+			trigger.Events = append(trigger.Events, "pull_request")
+			trigger.PullRequest = true
+		}
+	}
+	trigger.BranchInclude = append(trigger.BranchInclude, t.Branch.Include...)
+	trigger.BranchExclude = append(trigger.BranchExclude, t.Branch.Exclude...)
+	trigger.TagInclude = append(trigger.TagInclude, t.Tag.Include...)
+	trigger.TagExclude = append(trigger.TagExclude, t.Tag.Exclude...)
+}
+
+// toPublish converts p's non-nil publishing targets into Publish
+// entries, one per target, with Params round-tripped through YAML so
+// each target's fields land in the IR without hand-written mapping.
+func (p *Publishing) toPublish() []pipeline.Publish {
+	if p == nil {
+		return nil
+	}
+
+	var out []pipeline.Publish
+	add := func(kind string, target interface{}) {
+		if reflect.ValueOf(target).IsNil() {
+			return
+		}
+		out = append(out, pipeline.Publish{Kind: kind, Params: toParams(target)})
+	}
+	add("email", p.Email)
+	add("slack", p.Slack)
+	add("app_store_connect", p.AppStoreConnect)
+	add("google_play", p.GooglePlay)
+	add("firebase", p.Firebase)
+	add("github", p.Github)
+	return out
+}
+
+// toParams round-trips v through YAML to produce a plain
+// map[string]interface{}, so Publish.Params mirrors the YAML shape of
+// whichever publishing struct it came from without a field-by-field copy.
+func toParams(v interface{}) map[string]interface{} {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var params map[string]interface{}
+	if err := yaml.Unmarshal(data, &params); err != nil {
+		return nil
+	}
+	return params
+}
+
+// ParseCodemagic reads and parses the Codemagic config at path,
+// expanding ${VAR} / $VAR references in scalar values against the OS
+// environment. Use ParseCodemagicWithEnv for a custom mapping.
 func ParseCodemagic(path string) (*CodemagicYML, error) {
-	data, err := os.ReadFile(path)
+	return ParseCodemagicWithEnv(path, os.Getenv)
+}
+
+// ParseCodemagicWithEnv reads and parses the Codemagic config at path,
+// expanding ${VAR}, ${VAR:-default} and $VAR references in every scalar
+// against mapping before decoding, following the nfpm
+// ParseWithEnvMapping pattern.
+func ParseCodemagicWithEnv(path string, mapping func(string) string) (*CodemagicYML, error) {
+	f, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read Codemagic config file: %w", err)
 	}
+	defer f.Close()
 
-	var config CodemagicYML
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	return CodemagicFromReaderWithEnv(f, mapping)
+}
+
+// CodemagicFromReaderWithEnv is ParseCodemagicWithEnv for an
+// already-open reader: it decodes r into a YAML node tree, substitutes
+// env references in every scalar node via mapping, and only then
+// decodes the (now-substituted) tree into a CodemagicYML.
+func CodemagicFromReaderWithEnv(r io.Reader, mapping func(string) string) (*CodemagicYML, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Codemagic config: %w", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, fmt.Errorf("failed to parse Codemagic YAML: %w", err)
 	}
+	envsubst.Node(&root, mapping)
 
+	var config CodemagicYML
+	if err := root.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse Codemagic YAML: %w", err)
+	}
 	return &config, nil
+}
+
+func parseReader(r io.Reader) (*CodemagicYML, error) {
+	return CodemagicFromReaderWithEnv(r, os.Getenv)
+}
+
+// ciParser implements ciconfig.Parser so the codemagic package can
+// register itself with the ciconfig registry without callers having to
+// import codemagic directly.
+type ciParser struct{}
+
+func (ciParser) Format() string { return "codemagic" }
+
+// Detect reports whether path looks like a Codemagic config by name:
+// codemagic.yaml or codemagic.yml.
+func (ciParser) Detect(path string) bool {
+	base := strings.ToLower(filepath.Base(path))
+	return base == "codemagic.yaml" || base == "codemagic.yml"
+}
+
+func (ciParser) Parse(r io.Reader) (ciconfig.Config, error) {
+	return parseReader(r)
+}
+
+func init() {
+	ciconfig.RegisterParser("codemagic", ciParser{})
 }
\ No newline at end of file
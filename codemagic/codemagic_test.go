@@ -0,0 +1,250 @@
+package codemagic
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tuist/notary/ciconfig"
+	"github.com/tuist/notary/pipeline"
+)
+
+func TestParseCodemagic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "codemagic.yaml")
+	yamlContent := `
+workflows:
+  ios-workflow:
+    name: iOS Workflow
+    max_build_duration: 60
+    environment:
+      xcode: latest
+      vars:
+        APP_ID: com.example.app
+    scripts:
+      - name: Build
+        script: |
+          xcodebuild build
+    artifacts:
+      - build/ios/ipa/*.ipa
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	config, err := ParseCodemagic(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	workflow, ok := config.Workflows["ios-workflow"]
+	if !ok {
+		t.Fatal("expected workflow 'ios-workflow' to exist")
+	}
+	if workflow.MaxBuildDuration != 60 {
+		t.Errorf("expected max_build_duration 60, got %d", workflow.MaxBuildDuration)
+	}
+	if workflow.Environment.Vars["APP_ID"] != "com.example.app" {
+		t.Errorf("expected APP_ID var, got %+v", workflow.Environment.Vars)
+	}
+	if len(workflow.Artifacts) != 1 || workflow.Artifacts[0] != "build/ios/ipa/*.ipa" {
+		t.Errorf("expected one artifact glob, got %v", workflow.Artifacts)
+	}
+}
+
+func TestCodemagicYML_ToPipeline(t *testing.T) {
+	config := &CodemagicYML{
+		Workflows: map[string]Workflow{
+			"ios-workflow": {
+				Name: "iOS Workflow",
+				Environment: Environment{
+					Vars: map[string]string{"APP_ID": "com.example.app"},
+				},
+				Scripts: []Script{
+					{Name: "Build", Script: "xcodebuild build"},
+				},
+				Artifacts: []string{"build/ios/ipa/*.ipa"},
+				Triggering: &Triggering{
+					Events: []TriggerEvent{
+						{Push: &PushEvent{Branch: "main"}},
+						{PullRequest: &PullRequestEvent{TargetBranch: "main"}},
+					},
+					Branch: BranchPattern{Include: []string{"main"}},
+				},
+				Publishing: &Publishing{
+					Slack: &SlackPublishing{Channel: "#builds"},
+				},
+			},
+		},
+	}
+
+	p := config.ToPipeline()
+
+	if len(p.Stages) != 1 || p.Stages[0].Name != "ios-workflow" {
+		t.Fatalf("expected one stage named 'ios-workflow', got %+v", p.Stages)
+	}
+	steps := p.Stages[0].Steps
+	if len(steps) != 1 || steps[0].Script != "xcodebuild build" {
+		t.Fatalf("expected one script step, got %+v", steps)
+	}
+	if steps[0].Env["APP_ID"] != "com.example.app" {
+		t.Errorf("expected step env to carry workflow vars, got %+v", steps[0].Env)
+	}
+	if len(steps[0].Artifacts) != 1 || steps[0].Artifacts[0] != "build/ios/ipa/*.ipa" {
+		t.Errorf("expected artifacts on the stage's last step, got %v", steps[0].Artifacts)
+	}
+
+	if !p.Trigger.PullRequest {
+		t.Error("expected trigger to allow pull requests")
+	}
+	if len(p.Trigger.BranchInclude) != 1 || p.Trigger.BranchInclude[0] != "main" {
+		t.Errorf("expected branch include 'main', got %v", p.Trigger.BranchInclude)
+	}
+
+	if len(p.Publish) != 1 || p.Publish[0].Kind != "slack" {
+		t.Fatalf("expected one slack publish target, got %+v", p.Publish)
+	}
+	if p.Publish[0].Params["channel"] != "#builds" {
+		t.Errorf("expected slack channel param, got %+v", p.Publish[0].Params)
+	}
+}
+
+func TestParseCodemagicWithEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "codemagic.yaml")
+	yamlContent := `
+workflows:
+  ios-workflow:
+    name: iOS Workflow
+    environment:
+      vars:
+        APP_ID: ${APP_ID}
+        REGION: ${REGION:-us-east-1}
+    scripts:
+      - script: echo building
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	mapping := map[string]string{"APP_ID": "com.example.app"}
+	config, err := ParseCodemagicWithEnv(path, func(k string) string { return mapping[k] })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	vars := config.Workflows["ios-workflow"].Environment.Vars
+	if vars["APP_ID"] != "com.example.app" {
+		t.Errorf("expected APP_ID to expand, got %q", vars["APP_ID"])
+	}
+	if vars["REGION"] != "us-east-1" {
+		t.Errorf("expected REGION to fall back to default, got %q", vars["REGION"])
+	}
+}
+
+func TestExtractConcurrency(t *testing.T) {
+	config := &CodemagicYML{
+		Workflows: map[string]Workflow{
+			"pr-workflow": {
+				Triggering: &Triggering{
+					Events: []TriggerEvent{{PullRequest: &PullRequestEvent{}}},
+					Cancel: CancelPolicy{OnPullRequest: true},
+				},
+			},
+			"push-workflow": {
+				Triggering: &Triggering{
+					Events: []TriggerEvent{{Push: &PushEvent{}}},
+					Cancel: CancelPolicy{OnPush: false},
+				},
+			},
+			"no-triggering": {},
+		},
+	}
+
+	policies := ExtractConcurrency(config)
+
+	pr := policies["pr-workflow"]
+	if !pr.CancelInProgress || pr.Scope != pipeline.ScopePR || pr.Group != "pr-workflow" {
+		t.Errorf("unexpected pr-workflow policy: %+v", pr)
+	}
+
+	push := policies["push-workflow"]
+	if push.CancelInProgress || push.Scope != pipeline.ScopeBranch {
+		t.Errorf("unexpected push-workflow policy: %+v", push)
+	}
+
+	none := policies["no-triggering"]
+	if none.CancelInProgress || none.Scope != pipeline.ScopeBranch {
+		t.Errorf("expected default branch policy for workflow with no triggering, got %+v", none)
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data := JSONSchema()
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("generated schema is not valid JSON: %v", err)
+	}
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected properties map, got %T", schema["properties"])
+	}
+	if _, exists := properties["workflows"]; !exists {
+		t.Error("expected 'workflows' property in the generated schema")
+	}
+}
+
+func TestParseCodemagic_MissingFile(t *testing.T) {
+	if _, err := ParseCodemagic("/no/such/codemagic.yaml"); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestCiParserDetect(t *testing.T) {
+	p := ciParser{}
+	if p.Format() != "codemagic" {
+		t.Errorf("expected format 'codemagic', got %q", p.Format())
+	}
+
+	cases := map[string]bool{
+		"codemagic.yaml":     true,
+		"codemagic.yml":      true,
+		"bitrise.yml":        false,
+		"codemagic.yaml.bak": false,
+	}
+	for path, want := range cases {
+		if got := p.Detect(path); got != want {
+			t.Errorf("Detect(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCiParserParse(t *testing.T) {
+	p := ciParser{}
+	config, err := p.Parse(strings.NewReader("workflows:\n  ios-workflow:\n    scripts: []\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.(*CodemagicYML); !ok {
+		t.Errorf("expected *CodemagicYML, got %T", config)
+	}
+}
+
+func TestRegisteredWithCiconfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "codemagic.yaml")
+	if err := os.WriteFile(path, []byte("workflows:\n  ios-workflow:\n    scripts: []\n"), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	config, err := ciconfig.ParseFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := config.(*CodemagicYML); !ok {
+		t.Errorf("expected *CodemagicYML, got %T", config)
+	}
+}
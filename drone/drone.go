@@ -0,0 +1,181 @@
+// Package drone parses Drone/gokins-style pipeline YAML: one or more
+// `---`-separated documents, each with a top-level `kind: pipeline`,
+// `steps`, `trigger`, `volumes`, and `depends_on`, as described by the
+// drone-yaml jsonnet and gokins' bean/yml.go.
+package drone
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tuist/notary/ciconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Pipeline is one `kind: pipeline` document.
+type Pipeline struct {
+	Kind      string   `yaml:"kind"`
+	Type      string   `yaml:"type,omitempty"`
+	Name      string   `yaml:"name"`
+	Steps     []Step   `yaml:"steps,omitempty"`
+	Services  []Step   `yaml:"services,omitempty"`
+	Volumes   []Volume `yaml:"volumes,omitempty"`
+	DependsOn []string `yaml:"depends_on,omitempty"`
+	Trigger   Trigger  `yaml:"trigger,omitempty"`
+	When      When     `yaml:"when,omitempty"`
+}
+
+// Step is one pipeline or services step.
+type Step struct {
+	Name        string            `yaml:"name"`
+	Image       string            `yaml:"image,omitempty"`
+	Commands    []string          `yaml:"commands,omitempty"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+	Volumes     []VolumeMount     `yaml:"volumes,omitempty"`
+	DependsOn   []string          `yaml:"depends_on,omitempty"`
+	When        When              `yaml:"when,omitempty"`
+}
+
+// Volume is a pipeline-level named volume, backed by the host
+// filesystem or an in-memory temp filesystem.
+type Volume struct {
+	Name string      `yaml:"name"`
+	Host *HostVolume `yaml:"host,omitempty"`
+	Temp *TempVolume `yaml:"temp,omitempty"`
+}
+
+type HostVolume struct {
+	Path string `yaml:"path"`
+}
+
+type TempVolume struct {
+	Medium string `yaml:"medium,omitempty"`
+}
+
+// VolumeMount is a step's reference to a pipeline-level Volume by name.
+type VolumeMount struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+}
+
+// Trigger gates whether a pipeline runs at all for a given ref/event.
+type Trigger struct {
+	Ref    StringOrSlice `yaml:"ref,omitempty"`
+	Event  StringOrSlice `yaml:"event,omitempty"`
+	Branch Condition     `yaml:"branch,omitempty"`
+}
+
+// When gates whether a pipeline or step runs, same shape as Trigger but
+// used at the step level too.
+type When struct {
+	Branch Condition     `yaml:"branch,omitempty"`
+	Event  StringOrSlice `yaml:"event,omitempty"`
+}
+
+// Condition is Drone's include/exclude match list. It also accepts the
+// shorthand forms `branch: main` and `branch: [main, release/*]`, both
+// of which become Include.
+type Condition struct {
+	Include []string `yaml:"-"`
+	Exclude []string `yaml:"-"`
+}
+
+func (c *Condition) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		c.Include = []string{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		return value.Decode(&c.Include)
+	default:
+		type conditionAlias struct {
+			Include []string `yaml:"include,omitempty"`
+			Exclude []string `yaml:"exclude,omitempty"`
+		}
+		var alias conditionAlias
+		if err := value.Decode(&alias); err != nil {
+			return err
+		}
+		c.Include, c.Exclude = alias.Include, alias.Exclude
+		return nil
+	}
+}
+
+// StringOrSlice decodes either a single scalar or a YAML sequence of
+// strings into a []string, matching fields like `trigger.ref` and
+// `trigger.event` that accept both shorthands.
+type StringOrSlice []string
+
+func (s *StringOrSlice) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = StringOrSlice{value.Value}
+		return nil
+	}
+	var values []string
+	if err := value.Decode(&values); err != nil {
+		return err
+	}
+	*s = values
+	return nil
+}
+
+// ParseDrone reads path and parses every `---`-separated pipeline
+// document in it.
+func ParseDrone(path string) ([]Pipeline, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Drone config file: %w", err)
+	}
+	defer f.Close()
+
+	return parseReader(f)
+}
+
+// parseReader decodes r document by document with a yaml.Decoder, since
+// Drone concatenates multiple pipeline documents into one file with
+// `---` separators rather than a single top-level list.
+func parseReader(r io.Reader) ([]Pipeline, error) {
+	var pipelines []Pipeline
+
+	dec := yaml.NewDecoder(r)
+	for {
+		var p Pipeline
+		if err := dec.Decode(&p); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse Drone YAML: %w", err)
+		}
+		if p.Kind == "" {
+			// An empty document between two "---" separators.
+			continue
+		}
+		pipelines = append(pipelines, p)
+	}
+	return pipelines, nil
+}
+
+// ciParser implements ciconfig.Parser so the drone package can register
+// itself with the ciconfig registry without callers having to import
+// drone directly.
+type ciParser struct{}
+
+func (ciParser) Format() string { return "drone" }
+
+// Detect reports whether path looks like a Drone config by name:
+// .drone.yml or .drone.yaml.
+func (ciParser) Detect(path string) bool {
+	base := strings.ToLower(filepath.Base(path))
+	return base == ".drone.yml" || base == ".drone.yaml"
+}
+
+func (ciParser) Parse(r io.Reader) (ciconfig.Config, error) {
+	return parseReader(r)
+}
+
+func init() {
+	ciconfig.RegisterParser("drone", ciParser{})
+}
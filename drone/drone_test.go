@@ -0,0 +1,178 @@
+package drone
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tuist/notary/ciconfig"
+)
+
+func TestParseDrone_SinglePipeline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".drone.yml")
+	yamlContent := `
+kind: pipeline
+type: docker
+name: default
+
+steps:
+- name: build
+  image: golang
+  commands:
+  - go build ./...
+  environment:
+    CGO_ENABLED: "0"
+
+volumes:
+- name: cache
+  host:
+    path: /tmp/cache
+
+trigger:
+  branch:
+    include:
+    - main
+  event:
+  - push
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	pipelines, err := ParseDrone(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pipelines) != 1 {
+		t.Fatalf("expected 1 pipeline, got %d", len(pipelines))
+	}
+
+	p := pipelines[0]
+	if p.Name != "default" {
+		t.Errorf("expected name 'default', got %q", p.Name)
+	}
+	if len(p.Steps) != 1 || p.Steps[0].Image != "golang" {
+		t.Fatalf("expected one golang step, got %+v", p.Steps)
+	}
+	if p.Steps[0].Environment["CGO_ENABLED"] != "0" {
+		t.Errorf("expected CGO_ENABLED env, got %+v", p.Steps[0].Environment)
+	}
+	if len(p.Volumes) != 1 || p.Volumes[0].Host.Path != "/tmp/cache" {
+		t.Errorf("expected one host volume, got %+v", p.Volumes)
+	}
+	if len(p.Trigger.Branch.Include) != 1 || p.Trigger.Branch.Include[0] != "main" {
+		t.Errorf("expected trigger branch include 'main', got %+v", p.Trigger.Branch)
+	}
+	if len(p.Trigger.Event) != 1 || p.Trigger.Event[0] != "push" {
+		t.Errorf("expected trigger event 'push', got %+v", p.Trigger.Event)
+	}
+}
+
+func TestParseDrone_MultiDocument(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".drone.yml")
+	yamlContent := `
+kind: pipeline
+name: build
+steps:
+- name: build
+  image: golang
+---
+kind: pipeline
+name: deploy
+depends_on:
+- build
+steps:
+- name: deploy
+  image: alpine
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	pipelines, err := ParseDrone(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pipelines) != 2 {
+		t.Fatalf("expected 2 pipelines, got %d", len(pipelines))
+	}
+	if pipelines[1].Name != "deploy" || len(pipelines[1].DependsOn) != 1 || pipelines[1].DependsOn[0] != "build" {
+		t.Errorf("expected second pipeline 'deploy' depending on 'build', got %+v", pipelines[1])
+	}
+}
+
+func TestParseDrone_BranchShorthand(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".drone.yml")
+	yamlContent := `
+kind: pipeline
+name: default
+steps:
+- name: test
+  image: golang
+  when:
+    branch: main
+`
+	if err := os.WriteFile(path, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	pipelines, err := ParseDrone(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	when := pipelines[0].Steps[0].When
+	if len(when.Branch.Include) != 1 || when.Branch.Include[0] != "main" {
+		t.Errorf("expected branch shorthand to become Include, got %+v", when.Branch)
+	}
+}
+
+func TestParseDrone_FileNotFound(t *testing.T) {
+	if _, err := ParseDrone("/non/existent/.drone.yml"); err == nil {
+		t.Error("expected error for non-existent file")
+	}
+}
+
+func TestCiParserDetect(t *testing.T) {
+	p := ciParser{}
+	if p.Format() != "drone" {
+		t.Errorf("expected format 'drone', got %q", p.Format())
+	}
+
+	cases := map[string]bool{
+		".drone.yml":  true,
+		".drone.yaml": true,
+		"bitrise.yml": false,
+		"drone.yml":   false,
+	}
+	for path, want := range cases {
+		if got := p.Detect(path); got != want {
+			t.Errorf("Detect(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCiParserParse(t *testing.T) {
+	p := ciParser{}
+	config, err := p.Parse(strings.NewReader("kind: pipeline\nname: default\nsteps: []\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pipelines, ok := config.([]Pipeline)
+	if !ok || len(pipelines) != 1 {
+		t.Errorf("expected []Pipeline with 1 entry, got %T: %+v", config, config)
+	}
+}
+
+func TestRegisteredWithCiconfig(t *testing.T) {
+	p, err := ciconfig.Get("drone")
+	if err != nil {
+		t.Fatalf("expected drone parser to be registered: %v", err)
+	}
+	if p.Format() != "drone" {
+		t.Errorf("expected format 'drone', got %q", p.Format())
+	}
+}
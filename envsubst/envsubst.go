@@ -0,0 +1,52 @@
+// Package envsubst expands ${VAR}, ${VAR:-default} and $VAR references
+// against a caller-supplied mapping function, in plain strings and in
+// yaml.v3 node trees. It exists so every ciconfig provider parser can
+// offer a ParseXWithEnv variant without re-implementing the same
+// substitution regex.
+package envsubst
+
+import (
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// refPattern matches ${NAME}, ${NAME:-default} and bare $NAME.
+var refPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// String expands every ${NAME}, ${NAME:-default} and $NAME reference in
+// s using mapping. A reference whose mapping is empty resolves to its
+// :-default if one is given, otherwise to the empty string.
+func String(s string, mapping func(string) string) string {
+	return refPattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := refPattern.FindStringSubmatch(match)
+		name, hasDefault, def, bareName := groups[1], groups[2] != "", groups[3], groups[4]
+		if name == "" {
+			name = bareName
+		}
+
+		if v := mapping(name); v != "" {
+			return v
+		}
+		if hasDefault {
+			return def
+		}
+		return ""
+	})
+}
+
+// Node walks node's tree in place, expanding env references in every
+// string scalar via String. Call it on a decoded *yaml.Node before
+// decoding the node into a struct, so substitution happens ahead of
+// type resolution.
+func Node(node *yaml.Node, mapping func(string) string) {
+	if node == nil {
+		return
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		node.Value = String(node.Value, mapping)
+	}
+	for _, child := range node.Content {
+		Node(child, mapping)
+	}
+}
@@ -0,0 +1,59 @@
+package envsubst
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestString(t *testing.T) {
+	env := map[string]string{"NAME": "world", "EMPTY": ""}
+	mapping := func(k string) string { return env[k] }
+
+	testCases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"no refs", "hello", "hello"},
+		{"braced", "hello ${NAME}", "hello world"},
+		{"bare", "hello $NAME", "hello world"},
+		{"missing falls back to empty", "x${MISSING}y", "xy"},
+		{"default used when unset", "${MISSING:-fallback}", "fallback"},
+		{"default used when empty", "${EMPTY:-fallback}", "fallback"},
+		{"default ignored when set", "${NAME:-fallback}", "world"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := String(tc.in, mapping); got != tc.want {
+				t.Errorf("String(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNode(t *testing.T) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte("key: ${NAME}\nother: 1\n"), &root); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	Node(&root, func(k string) string {
+		if k == "NAME" {
+			return "world"
+		}
+		return ""
+	})
+
+	var decoded map[string]interface{}
+	if err := root.Decode(&decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decoded["key"] != "world" {
+		t.Errorf("expected key to expand to 'world', got %v", decoded["key"])
+	}
+	if decoded["other"] != 1 {
+		t.Errorf("expected non-string scalar to be left alone, got %v", decoded["other"])
+	}
+}
@@ -0,0 +1,165 @@
+// Package events defines the line-delimited JSON event stream notary
+// emits with `--log-format json` (see the run and bitrise run commands):
+// one JSON object per line describing the run plan and every state
+// transition, so editors and dashboards can follow a run without
+// scraping the human-readable prose output.
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Emitter writes events to w as line-delimited JSON. It is safe for
+// concurrent use: planner.Plan.Execute runs jobs within a batch on their
+// own goroutines, each writing step logs and reporter events through
+// the same Emitter.
+type Emitter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewEmitter builds an Emitter that writes to w.
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w}
+}
+
+// Emit marshals event to JSON and writes it to the underlying writer,
+// followed by a newline.
+func (e *Emitter) Emit(event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.w.Write(data)
+	return err
+}
+
+// StreamWriter adapts an Emitter into an io.Writer: every Write becomes
+// a "step_log" event tagged with stream ("stdout" or "stderr"), so a
+// runtime backend can point a running step's output straight at the
+// JSON event stream instead of the terminal.
+type StreamWriter struct {
+	emitter *Emitter
+	stream  string
+}
+
+// NewStreamWriter builds a StreamWriter that emits step_log events
+// through emitter, tagged with stream.
+func NewStreamWriter(emitter *Emitter, stream string) *StreamWriter {
+	return &StreamWriter{emitter: emitter, stream: stream}
+}
+
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	if err := s.emitter.Emit(NewStepLog(s.stream, string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Plan describes every job a run will execute: its matrix expansion,
+// container image and services, needs, and (for Bitrise) which step
+// bundle each of its steps came from.
+type Plan struct {
+	Type string    `json:"type"`
+	Jobs []JobPlan `json:"jobs"`
+	// RuntimeFallback reports that the requested runtime (e.g. docker)
+	// was unavailable and the run fell back to the host runtime.
+	RuntimeFallback bool `json:"runtime_fallback,omitempty"`
+}
+
+// JobPlan is one entry in a Plan event.
+type JobPlan struct {
+	Job       string                 `json:"job"`
+	Name      string                 `json:"name,omitempty"`
+	Matrix    map[string]interface{} `json:"matrix,omitempty"`
+	Container string                 `json:"container,omitempty"`
+	Services  []string               `json:"services,omitempty"`
+	Needs     []string               `json:"needs,omitempty"`
+	Steps     []StepPlan             `json:"steps,omitempty"`
+}
+
+// StepPlan describes one step within a JobPlan, including the step
+// bundle it was expanded from, if any.
+type StepPlan struct {
+	Step   int    `json:"step"`
+	Uses   string `json:"uses,omitempty"`
+	Run    string `json:"run,omitempty"`
+	Bundle string `json:"bundle,omitempty"`
+}
+
+// NewPlan builds a "plan" event from jobs.
+func NewPlan(jobs []JobPlan) Plan {
+	return Plan{Type: "plan", Jobs: jobs}
+}
+
+// JobStarted reports that job (optionally one matrix instance of it)
+// has begun executing.
+type JobStarted struct {
+	Type   string                 `json:"type"`
+	Job    string                 `json:"job"`
+	Matrix map[string]interface{} `json:"matrix,omitempty"`
+}
+
+// NewJobStarted builds a "job_started" event.
+func NewJobStarted(job string, matrix map[string]interface{}) JobStarted {
+	return JobStarted{Type: "job_started", Job: job, Matrix: matrix}
+}
+
+// StepStarted reports that step index of job has begun executing.
+type StepStarted struct {
+	Type   string `json:"type"`
+	Job    string `json:"job"`
+	Step   int    `json:"step"`
+	Uses   string `json:"uses,omitempty"`
+	Run    string `json:"run,omitempty"`
+	Bundle string `json:"bundle,omitempty"`
+}
+
+// NewStepStarted builds a "step_started" event.
+func NewStepStarted(job string, step int, uses, run, bundle string) StepStarted {
+	return StepStarted{Type: "step_started", Job: job, Step: step, Uses: uses, Run: run, Bundle: bundle}
+}
+
+// StepLog carries one chunk of a running step's stdout or stderr.
+type StepLog struct {
+	Type   string `json:"type"`
+	Stream string `json:"stream"`
+	Data   string `json:"data"`
+}
+
+// NewStepLog builds a "step_log" event for a chunk of output read from
+// stream ("stdout" or "stderr").
+func NewStepLog(stream, data string) StepLog {
+	return StepLog{Type: "step_log", Stream: stream, Data: data}
+}
+
+// StepFinished reports a step's outcome.
+type StepFinished struct {
+	Type       string `json:"type"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// NewStepFinished builds a "step_finished" event.
+func NewStepFinished(exitCode int, durationMs int64) StepFinished {
+	return StepFinished{Type: "step_finished", ExitCode: exitCode, DurationMs: durationMs}
+}
+
+// JobFinished reports a job's final conclusion ("success" or
+// "failure").
+type JobFinished struct {
+	Type       string `json:"type"`
+	Job        string `json:"job"`
+	Conclusion string `json:"conclusion"`
+}
+
+// NewJobFinished builds a "job_finished" event.
+func NewJobFinished(job string, conclusion string) JobFinished {
+	return JobFinished{Type: "job_finished", Job: job, Conclusion: conclusion}
+}
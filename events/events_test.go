@@ -0,0 +1,115 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestEmitterWritesLineDelimitedJSON(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter(&buf)
+
+	if err := e.Emit(NewJobStarted("build", map[string]interface{}{"os": "linux"})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := e.Emit(NewJobFinished("build", "success")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("expected valid JSON, got error: %v", err)
+	}
+	if first["type"] != "job_started" || first["job"] != "build" {
+		t.Errorf("unexpected first event: %v", first)
+	}
+}
+
+func TestPlanOmitsRuntimeFallbackByDefault(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter(&buf)
+
+	if err := e.Emit(NewPlan([]JobPlan{{Job: "build"}})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimRight(buf.Bytes(), "\n"), &event); err != nil {
+		t.Fatalf("expected valid JSON, got error: %v", err)
+	}
+	if _, exists := event["runtime_fallback"]; exists {
+		t.Errorf("expected runtime_fallback to be omitted when false, got %v", event)
+	}
+}
+
+func TestPlanReportsRuntimeFallback(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter(&buf)
+
+	plan := NewPlan([]JobPlan{{Job: "build"}})
+	plan.RuntimeFallback = true
+	if err := e.Emit(plan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimRight(buf.Bytes(), "\n"), &event); err != nil {
+		t.Fatalf("expected valid JSON, got error: %v", err)
+	}
+	if event["runtime_fallback"] != true {
+		t.Errorf("expected runtime_fallback true, got %v", event)
+	}
+}
+
+func TestEmitterConcurrentEmitProducesValidLines(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter(&buf)
+
+	const goroutines = 20
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(i int) {
+			defer wg.Done()
+			_ = e.Emit(NewStepLog("stdout", strings.Repeat("x", 4096)))
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != goroutines {
+		t.Fatalf("expected %d lines, got %d", goroutines, len(lines))
+	}
+	for _, line := range lines {
+		var event map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("expected every line to be valid, independent JSON, got error: %v\nline: %q", err, line)
+		}
+	}
+}
+
+func TestStreamWriterEmitsStepLog(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewEmitter(&buf)
+	w := NewStreamWriter(e, "stdout")
+
+	if _, err := w.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var event map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimRight(buf.Bytes(), "\n"), &event); err != nil {
+		t.Fatalf("expected valid JSON, got error: %v", err)
+	}
+	if event["type"] != "step_log" || event["stream"] != "stdout" || event["data"] != "hello\n" {
+		t.Errorf("unexpected step_log event: %v", event)
+	}
+}
@@ -0,0 +1,105 @@
+// Package jsonschema generates JSON Schema documents from yaml-tagged
+// Go structs, in the same spirit as goreleaser's published schema.json:
+// point it at a provider's root config type and it walks the struct
+// tree into a schema an editor can use for autocompletion and
+// validation.
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// SchemaProvider is implemented by types whose custom UnmarshalYAML
+// turns a YAML shape (a single-key map, a scalar-or-object, ...) into a
+// Go struct that doesn't reflect back into a meaningful schema on its
+// own. Generate calls JSONSchema() for such a type instead of
+// reflecting its fields.
+type SchemaProvider interface {
+	JSONSchema() map[string]interface{}
+}
+
+// Generate builds a JSON Schema document for v's type (an instance of,
+// or pointer to, a root config struct such as bitrise.BitriseYML) and
+// marshals it to indented JSON.
+func Generate(v interface{}) []byte {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema := map[string]interface{}{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+	}
+	for k, v := range schemaFor(t) {
+		schema[k] = v
+	}
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		// schemaFor only ever produces JSON-marshalable values
+		// (maps, slices, strings, bools), so this can't happen.
+		panic("jsonschema: " + err.Error())
+	}
+	return data
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		return schemaFor(t.Elem())
+	}
+	if provider, ok := reflect.Zero(t).Interface().(SchemaProvider); ok {
+		return provider.JSONSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return structSchema(t)
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem()),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		// reflect.Interface and anything else (e.g. map[string]interface{}
+		// meta fields) accepts any JSON value.
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema builds an "object" schema from t's exported fields,
+// keyed by their yaml tag name (falling back to the lowercased field
+// name for untagged fields, and skipping yaml:"-" fields).
+func structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		properties[name] = schemaFor(field.Type)
+	}
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+}
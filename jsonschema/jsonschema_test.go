@@ -0,0 +1,77 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type sampleProvider struct{}
+
+func (sampleProvider) JSONSchema() map[string]interface{} {
+	return map[string]interface{}{"type": "string", "description": "custom"}
+}
+
+type sampleStruct struct {
+	Name     string            `yaml:"name,omitempty"`
+	Tags     []string          `yaml:"tags,omitempty"`
+	Vars     map[string]string `yaml:"vars,omitempty"`
+	Internal string            `yaml:"-"`
+	Custom   sampleProvider    `yaml:"custom,omitempty"`
+}
+
+func TestGenerate(t *testing.T) {
+	data := Generate(sampleStruct{})
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("generated schema is not valid JSON: %v", err)
+	}
+
+	if schema["$schema"] != "http://json-schema.org/draft-07/schema#" {
+		t.Errorf("expected $schema to be set, got %v", schema["$schema"])
+	}
+	if schema["type"] != "object" {
+		t.Errorf("expected root type 'object', got %v", schema["type"])
+	}
+
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected properties map, got %T", schema["properties"])
+	}
+
+	if _, exists := properties["internal"]; exists {
+		t.Error("expected yaml:\"-\" field to be skipped")
+	}
+
+	name, ok := properties["name"].(map[string]interface{})
+	if !ok || name["type"] != "string" {
+		t.Errorf("expected name property of type string, got %v", properties["name"])
+	}
+
+	tags, ok := properties["tags"].(map[string]interface{})
+	if !ok || tags["type"] != "array" {
+		t.Errorf("expected tags property of type array, got %v", properties["tags"])
+	}
+
+	vars, ok := properties["vars"].(map[string]interface{})
+	if !ok || vars["type"] != "object" {
+		t.Errorf("expected vars property of type object, got %v", properties["vars"])
+	}
+
+	custom, ok := properties["custom"].(map[string]interface{})
+	if !ok || custom["description"] != "custom" {
+		t.Errorf("expected custom property to use its SchemaProvider schema, got %v", properties["custom"])
+	}
+}
+
+func TestGenerate_Pointer(t *testing.T) {
+	data := Generate(&sampleStruct{})
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("generated schema is not valid JSON: %v", err)
+	}
+	if schema["type"] != "object" {
+		t.Errorf("expected a pointer input to generate the same schema, got %v", schema["type"])
+	}
+}
@@ -1,37 +1,27 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
+
+	"github.com/tuist/notary/bitrise"
+	"github.com/tuist/notary/bitrise/runner"
+	"github.com/tuist/notary/events"
+	"github.com/tuist/notary/github"
+	"github.com/tuist/notary/planner"
+	"github.com/tuist/notary/runtime"
 )
 
 const version = "0.1.0"
 
-type WorkflowConfig struct {
-	Name string               `yaml:"name"`
-	On   interface{}          `yaml:"on"`
-	Jobs map[string]JobConfig `yaml:"jobs"`
-}
-
-type JobConfig struct {
-	RunsOn string       `yaml:"runs-on"`
-	Steps  []StepConfig `yaml:"steps"`
-}
-
-type StepConfig struct {
-	Name string                 `yaml:"name"`
-	Uses string                 `yaml:"uses"`
-	Run  string                 `yaml:"run"`
-	With map[string]interface{} `yaml:"with"`
-	Env  map[string]string      `yaml:"env"`
-}
-
 var rootCmd = &cobra.Command{
 	Use:     "notary",
 	Short:   "Run GitHub and Forgejo Actions workflows locally",
@@ -40,31 +30,65 @@ var rootCmd = &cobra.Command{
 }
 
 var runCmd = &cobra.Command{
-	Use:   "run [workflow]",
-	Short: "Run a workflow locally",
-	Long:  `Run a GitHub or Forgejo Actions workflow locally in your development environment.`,
-	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		workflowFile := ".github/workflows/ci.yml"
+	Use:   "run [workflows-dir]",
+	Short: "Plan and run the workflows that match an event",
+	Long: `Run plans every workflow under workflows-dir (.github/workflows by
+default) whose "on:" matches the target event, resolves job dependencies
+and matrix expansion, and executes the resulting plan job by job.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if format, _ := cmd.Flags().GetString("format"); format == "bitrise" {
+			return runBitrise(cmd, args)
+		}
+
+		workflowsDir := ".github/workflows"
 		if len(args) > 0 {
-			workflowFile = args[0]
+			workflowsDir = args[0]
+		}
+
+		event, _ := cmd.Flags().GetString("event")
+		eventFile, _ := cmd.Flags().GetString("event-file")
+		runtimeName, _ := cmd.Flags().GetString("runtime")
+		logFormat, _ := cmd.Flags().GetString("log-format")
+
+		payload, err := loadEventPayload(eventFile)
+		if err != nil {
+			return err
 		}
 
-		workflow, err := loadWorkflow(workflowFile)
+		plan, err := planner.PlanEvent(workflowsDir, event, payload)
 		if err != nil {
-			log.Fatalf("Failed to load workflow: %v", err)
+			return fmt.Errorf("failed to plan workflow run: %w", err)
 		}
 
-		fmt.Printf("🚀 Running workflow: %s\n", workflow.Name)
+		rt, ok := runtime.New(runtimeName)
 
-		for jobName, job := range workflow.Jobs {
-			fmt.Printf("\n📦 Job: %s\n", jobName)
-			if err := runJob(jobName, job); err != nil {
-				log.Fatalf("Job %s failed: %v", jobName, err)
+		opts := planner.ExecuteOptions{EventName: event}
+		if logFormat == "json" {
+			emitter := events.NewEmitter(os.Stdout)
+			planEvent := describeGithubPlan(plan)
+			planEvent.RuntimeFallback = !ok
+			if err := emitter.Emit(planEvent); err != nil {
+				return err
 			}
+			opts.Reporter = &jsonReporter{emitter: emitter}
+			opts.Stdout = events.NewStreamWriter(emitter, "stdout")
+			opts.Stderr = events.NewStreamWriter(emitter, "stderr")
+		} else {
+			if !ok {
+				fmt.Printf("⚠️  Docker not available, falling back to host runtime\n")
+			}
+			fmt.Printf("🚀 Running %d job(s) for event %q (%s runtime)\n", countJobs(plan), event, rt.Name())
 		}
 
-		fmt.Println("\n✅ Workflow completed successfully!")
+		if err := plan.Execute(rt, opts); err != nil {
+			return err
+		}
+
+		if logFormat != "json" {
+			fmt.Println("\n✅ Workflow completed successfully!")
+		}
+		return nil
 	},
 }
 
@@ -74,6 +98,15 @@ var signoffCmd = &cobra.Command{
 	Long:  `Sign off the current commit using the GitHub CLI after successful local workflow execution.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		message, _ := cmd.Flags().GetString("message")
+		eventsFile, _ := cmd.Flags().GetString("events-file")
+
+		if eventsFile != "" {
+			summary, err := summarizeEvents(eventsFile)
+			if err != nil {
+				log.Fatalf("Failed to summarize %s: %v", eventsFile, err)
+			}
+			message = summary
+		}
 
 		fmt.Println("📝 Signing off commit...")
 
@@ -121,7 +154,7 @@ var listCmd = &cobra.Command{
 
 		fmt.Println("📋 Available workflows:")
 		for _, file := range files {
-			workflow, err := loadWorkflow(file)
+			workflow, err := github.ParseWorkflow(file)
 			if err != nil {
 				fmt.Printf("  ❌ %s (error loading)\n", filepath.Base(file))
 				continue
@@ -137,6 +170,17 @@ func init() {
 	rootCmd.AddCommand(listCmd)
 
 	signoffCmd.Flags().StringP("message", "m", "CI passed locally", "Sign-off message")
+
+	runCmd.Flags().String("event", "push", "Event name to plan for (push, pull_request, workflow_dispatch, ...)")
+	runCmd.Flags().String("event-file", "", "Path to a JSON file with the event payload (exposed as github.event.*)")
+	runCmd.Flags().String("runtime", "host", "Backend to execute steps with: host or docker")
+	runCmd.Flags().String("format", "github", "Workflow format to run: github or bitrise")
+	runCmd.Flags().String("workflow", "primary", "Bitrise workflow name to run (--format bitrise)")
+	runCmd.Flags().String("steplib", "https://github.com/bitrise-io/bitrise-steplib.git", "Steplib URL to activate Bitrise steps from (--format bitrise)")
+	runCmd.Flags().Bool("offline", false, "Fail fast instead of cloning/updating the steplib and step caches over the network (--format bitrise)")
+	runCmd.Flags().String("log-format", "text", "Output format: text (prose) or json (one JSON event per line)")
+
+	signoffCmd.Flags().String("events-file", "", "Path to a --log-format json event stream to summarize as the sign-off description")
 }
 
 func main() {
@@ -146,55 +190,176 @@ func main() {
 	}
 }
 
-func loadWorkflow(path string) (*WorkflowConfig, error) {
+func loadEventPayload(path string) (planner.EventPayload, error) {
+	if path == "" {
+		return nil, nil
+	}
+
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read workflow file: %w", err)
+		return nil, fmt.Errorf("failed to read event file: %w", err)
+	}
+
+	var payload planner.EventPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse event file: %w", err)
+	}
+	return payload, nil
+}
+
+// summarizeEvents reads a --log-format json event stream and renders its
+// job_finished events into a short sign-off description, e.g. "4/4 jobs
+// passed" or "3/4 jobs passed (failed: test)".
+func summarizeEvents(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read events file: %w", err)
+	}
+
+	var total, passed int
+	var failedJobs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var event struct {
+			Type       string `json:"type"`
+			Job        string `json:"job"`
+			Conclusion string `json:"conclusion"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return "", fmt.Errorf("failed to parse event line %q: %w", line, err)
+		}
+		if event.Type != "job_finished" {
+			continue
+		}
+		total++
+		if event.Conclusion == "success" {
+			passed++
+		} else {
+			failedJobs = append(failedJobs, event.Job)
+		}
 	}
 
-	var workflow WorkflowConfig
-	if err := yaml.Unmarshal(data, &workflow); err != nil {
-		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	summary := fmt.Sprintf("%d/%d jobs passed", passed, total)
+	if len(failedJobs) > 0 {
+		summary += fmt.Sprintf(" (failed: %s)", strings.Join(failedJobs, ", "))
 	}
+	return summary, nil
+}
 
-	return &workflow, nil
+func countJobs(plan *planner.Plan) int {
+	count := 0
+	for _, batch := range plan.Batches {
+		count += len(batch)
+	}
+	return count
 }
 
-func runJob(name string, job JobConfig) error {
-	for i, step := range job.Steps {
-		fmt.Printf("  Step %d/%d: %s\n", i+1, len(job.Steps), step.Name)
+func runBitrise(cmd *cobra.Command, args []string) error {
+	path := "bitrise.yml"
+	if len(args) > 0 {
+		path = args[0]
+	}
 
-		if step.Run != "" {
-			// Execute shell commands
-			if err := runCommand(step.Run, step.Env); err != nil {
-				return fmt.Errorf("step '%s' failed: %w", step.Name, err)
-			}
-		} else if step.Uses != "" {
-			// Handle action references (simplified)
-			fmt.Printf("    Using action: %s\n", step.Uses)
-			if step.Uses == "actions/checkout@v4" || step.Uses == "actions/checkout@v3" {
-				// Simulate checkout - in local env, we're already in the repo
-				fmt.Println("    ✓ Repository already checked out (local)")
-			} else {
-				fmt.Printf("    ⚠️  Action %s would run in CI environment\n", step.Uses)
+	workflowName, _ := cmd.Flags().GetString("workflow")
+	steplibURL, _ := cmd.Flags().GetString("steplib")
+	offline, _ := cmd.Flags().GetBool("offline")
+	logFormat, _ := cmd.Flags().GetString("log-format")
+
+	config, err := bitrise.ParseBitrise(path)
+	if err != nil {
+		return fmt.Errorf("failed to load Bitrise config: %w", err)
+	}
+
+	home, err := notaryHomeDir()
+	if err != nil {
+		return err
+	}
+	activator := runner.NewStepActivator(steplibURL, home, offline)
+	r := runner.NewRunner(activator)
+
+	if logFormat == "json" {
+		r.Emitter = events.NewEmitter(os.Stdout)
+	} else {
+		fmt.Printf("🚀 Running Bitrise workflow: %s\n", workflowName)
+	}
+
+	if err := r.RunWorkflow(config, workflowName); err != nil {
+		return err
+	}
+
+	if logFormat != "json" {
+		fmt.Println("\n✅ Workflow completed successfully!")
+	}
+	return nil
+}
+
+// describeGithubPlan turns plan into a "plan" event, one JobPlan per
+// matrix instance, with its container image, service names, and needs.
+func describeGithubPlan(plan *planner.Plan) events.Plan {
+	var jobs []events.JobPlan
+	for _, batch := range plan.Batches {
+		for _, group := range batch {
+			for _, instance := range group.Instances {
+				jobPlan := events.JobPlan{
+					Job:    instance.JobID,
+					Name:   instance.Name,
+					Matrix: instance.Matrix,
+					Needs:  instance.Needs,
+				}
+				if instance.Job.Container != nil {
+					jobPlan.Container = instance.Job.Container.Image
+				}
+				for name := range instance.Job.Services {
+					jobPlan.Services = append(jobPlan.Services, name)
+				}
+				for i, step := range instance.Job.Steps {
+					jobPlan.Steps = append(jobPlan.Steps, events.StepPlan{Step: i, Uses: step.Uses, Run: step.Run})
+				}
+				jobs = append(jobs, jobPlan)
 			}
 		}
 	}
-	return nil
+	return events.NewPlan(jobs)
 }
 
-func runCommand(command string, env map[string]string) error {
-	// Use sh to execute the command
-	cmd := exec.Command("sh", "-c", command)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+// jsonReporter drives notary run --log-format json's JSON event stream
+// from planner.Plan.Execute's state transitions.
+type jsonReporter struct {
+	emitter *events.Emitter
+}
 
-	// Set environment variables
-	cmd.Env = os.Environ()
-	for k, v := range env {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+func (r *jsonReporter) JobStarted(instance *planner.JobInstance) {
+	r.emitter.Emit(events.NewJobStarted(instance.JobID, instance.Matrix))
+}
+
+func (r *jsonReporter) StepStarted(instance *planner.JobInstance, index int, step github.Step) {
+	r.emitter.Emit(events.NewStepStarted(instance.JobID, index, step.Uses, step.Run, ""))
+}
+
+func (r *jsonReporter) StepFinished(instance *planner.JobInstance, index int, step github.Step, err error, duration time.Duration) {
+	exitCode := 0
+	if err != nil {
+		exitCode = 1
 	}
+	r.emitter.Emit(events.NewStepFinished(exitCode, duration.Milliseconds()))
+}
 
-	return cmd.Run()
+func (r *jsonReporter) JobFinished(instance *planner.JobInstance, err error) {
+	conclusion := "success"
+	if err != nil {
+		conclusion = "failure"
+	}
+	r.emitter.Emit(events.NewJobFinished(instance.JobID, conclusion))
+}
+
+// notaryHomeDir is where notary caches steplib clones and activated step
+// sources, i.e. ~/.notary.
+func notaryHomeDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve home directory: %w", err)
+	}
+	return filepath.Join(home, ".notary"), nil
 }
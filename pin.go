@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tuist/notary/planner"
+)
+
+var pinCmd = &cobra.Command{
+	Use:   "pin [workflows-dir]",
+	Short: "Pin action references to a commit SHA",
+	Long: `Pin rewrites every "uses:" in workflows-dir (.github/workflows by
+default) from a tag or branch ref to the full commit SHA it currently
+resolves to, appending the original ref as a trailing comment so
+"notary unpin" can restore it. This closes the supply-chain gap where a
+tag can be moved to point at different code after review.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workflowsDir := ".github/workflows"
+		if len(args) > 0 {
+			workflowsDir = args[0]
+		}
+		allow, _ := cmd.Flags().GetStringSlice("allow")
+		check, _ := cmd.Flags().GetBool("check")
+
+		files, err := planner.WorkflowFiles(workflowsDir)
+		if err != nil {
+			return err
+		}
+
+		if check {
+			return checkPinned(files, allow)
+		}
+
+		token := githubToken()
+		for _, file := range files {
+			if err := rewriteUses(file, func(ref usesRef, comment string) (string, string, error) {
+				return pinRef(ref, token)
+			}); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		}
+		return nil
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin [workflows-dir]",
+	Short: "Restore human-readable refs pinned by notary pin",
+	Long: `Unpin restores every "uses:" previously pinned by "notary pin" back
+to the tag/branch ref recorded in its trailing comment.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workflowsDir := ".github/workflows"
+		if len(args) > 0 {
+			workflowsDir = args[0]
+		}
+
+		files, err := planner.WorkflowFiles(workflowsDir)
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if err := rewriteUses(file, unpinRef); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+
+	pinCmd.Flags().Bool("check", false, "Exit non-zero if any uses: is not pinned to a commit SHA, without rewriting")
+	pinCmd.Flags().StringSlice("allow", nil, "Comma-separated list of action owners that don't need to be pinned")
+}
+
+// usesRef is a parsed `uses:` value.
+type usesRef struct {
+	Kind  string // "action", "docker", or "local"
+	Owner string // action only
+	Repo  string // action only
+	Ref   string // tag/branch (action) or tag (docker)
+	Image string // docker only
+}
+
+var (
+	actionRefRe = regexp.MustCompile(`^([^/]+)/([^@]+)@(.+)$`)
+	shaRe       = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	digestRe    = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+func parseUsesRef(uses string) (usesRef, bool) {
+	switch {
+	case strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "../"):
+		return usesRef{Kind: "local"}, true
+	case strings.HasPrefix(uses, "docker://"):
+		image := strings.TrimPrefix(uses, "docker://")
+		name, ref := splitDockerRef(image)
+		return usesRef{Kind: "docker", Image: name, Ref: ref}, true
+	default:
+		m := actionRefRe.FindStringSubmatch(uses)
+		if m == nil {
+			return usesRef{}, false
+		}
+		return usesRef{Kind: "action", Owner: m[1], Repo: m[2], Ref: m[3]}, true
+	}
+}
+
+func splitDockerRef(image string) (name, ref string) {
+	if i := strings.LastIndex(image, "@"); i != -1 {
+		return image[:i], image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i != -1 && i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+func isPinned(ref usesRef) bool {
+	switch ref.Kind {
+	case "action":
+		return shaRe.MatchString(ref.Ref)
+	case "docker":
+		return digestRe.MatchString(ref.Ref)
+	default:
+		return true
+	}
+}
+
+// rewriteUses parses path into a yaml.Node document (preserving key
+// order, comments, and indentation), applies transform to every
+// `uses:` scalar, and writes the document back if anything changed.
+func rewriteUses(path string, transform func(ref usesRef, comment string) (string, string, error)) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	changed := false
+	var walkErr error
+	walkUsesNodes(&root, func(valueNode *yaml.Node) {
+		if walkErr != nil {
+			return
+		}
+		ref, ok := parseUsesRef(valueNode.Value)
+		if !ok || ref.Kind == "local" {
+			return
+		}
+		newValue, newComment, err := transform(ref, valueNode.LineComment)
+		if err != nil {
+			walkErr = fmt.Errorf("%s: %w", valueNode.Value, err)
+			return
+		}
+		if newValue == "" {
+			return
+		}
+		if newValue != valueNode.Value || newComment != valueNode.LineComment {
+			valueNode.Value = newValue
+			valueNode.LineComment = newComment
+			changed = true
+		}
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	if !changed {
+		return nil
+	}
+
+	out, err := yaml.Marshal(&root)
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", path, err)
+	}
+	return os.WriteFile(path, out, 0o644)
+}
+
+// walkUsesNodes calls fn with the value node of every `uses:` mapping
+// entry found anywhere under node.
+func walkUsesNodes(node *yaml.Node, fn func(valueNode *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			walkUsesNodes(child, fn)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "uses" && value.Kind == yaml.ScalarNode {
+				fn(value)
+			}
+			walkUsesNodes(value, fn)
+		}
+	}
+}
+
+func pinRef(ref usesRef, token string) (value, comment string, err error) {
+	if isPinned(ref) {
+		return "", "", nil
+	}
+
+	switch ref.Kind {
+	case "action":
+		sha, err := resolveActionSHA(ref.Owner, ref.Repo, ref.Ref, token)
+		if err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("%s/%s@%s", ref.Owner, ref.Repo, sha), "# " + ref.Ref, nil
+	case "docker":
+		digest, err := resolveDockerDigest(ref.Image, ref.Ref)
+		if err != nil {
+			return "", "", err
+		}
+		return fmt.Sprintf("docker://%s@%s", ref.Image, digest), "# " + ref.Ref, nil
+	default:
+		return "", "", nil
+	}
+}
+
+var pinnedCommentRe = regexp.MustCompile(`^#\s*(\S+)`)
+
+// unpinRef restores the human-readable ref pin left in the trailing
+// comment. A pinned uses: with no such comment wasn't pinned by notary
+// and is left untouched.
+func unpinRef(ref usesRef, comment string) (value, newComment string, err error) {
+	if !isPinned(ref) {
+		return "", "", nil
+	}
+	m := pinnedCommentRe.FindStringSubmatch(comment)
+	if m == nil {
+		return "", "", nil
+	}
+	originalRef := m[1]
+
+	switch ref.Kind {
+	case "action":
+		return fmt.Sprintf("%s/%s@%s", ref.Owner, ref.Repo, originalRef), "", nil
+	case "docker":
+		return fmt.Sprintf("docker://%s:%s", ref.Image, originalRef), "", nil
+	default:
+		return "", "", nil
+	}
+}
+
+func checkPinned(files []string, allow []string) error {
+	allowed := map[string]bool{}
+	for _, owner := range allow {
+		allowed[owner] = true
+	}
+
+	var violations []string
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var root yaml.Node
+		if err := yaml.Unmarshal(data, &root); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		walkUsesNodes(&root, func(valueNode *yaml.Node) {
+			ref, ok := parseUsesRef(valueNode.Value)
+			if !ok || ref.Kind == "local" {
+				return
+			}
+			if ref.Kind == "action" && allowed[ref.Owner] {
+				return
+			}
+			if !isPinned(ref) {
+				violations = append(violations, fmt.Sprintf("%s: %s", file, valueNode.Value))
+			}
+		})
+	}
+
+	if len(violations) > 0 {
+		for _, v := range violations {
+			fmt.Println("❌ not pinned:", v)
+		}
+		return fmt.Errorf("%d action reference(s) are not pinned to a commit SHA", len(violations))
+	}
+	fmt.Println("✅ all action references are pinned")
+	return nil
+}
+
+// githubToken resolves a token to call the GitHub API with, preferring
+// `gh auth token` (so an interactively-authenticated gh CLI just works)
+// and falling back to GITHUB_TOKEN.
+func githubToken() string {
+	if out, err := exec.Command("gh", "auth", "token").Output(); err == nil {
+		if token := strings.TrimSpace(string(out)); token != "" {
+			return token
+		}
+	}
+	return os.Getenv("GITHUB_TOKEN")
+}
+
+func resolveActionSHA(owner, repo, ref, token string) (string, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits/%s", owner, repo, ref)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s/%s@%s: %w", owner, repo, ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s/%s@%s: GitHub API returned %s", owner, repo, ref, resp.Status)
+	}
+
+	var body struct {
+		SHA string `json:"sha"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode GitHub API response for %s/%s@%s: %w", owner, repo, ref, err)
+	}
+	return body.SHA, nil
+}
+
+// resolveDockerDigest resolves image:tag to its manifest digest via the
+// OCI distribution API, authenticating against Docker Hub's anonymous
+// token endpoint when image has no registry host of its own.
+func resolveDockerDigest(image, tag string) (string, error) {
+	registry, repoPath := dockerRegistryAndPath(image)
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/v2/%s/manifests/%s", registry, repoPath, tag), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+
+	if token, err := dockerRegistryToken(registry, repoPath); err == nil && token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve docker digest for %s:%s: %w", image, tag, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve docker digest for %s:%s: registry returned %s", image, tag, resp.Status)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("registry did not return a Docker-Content-Digest header for %s:%s", image, tag)
+	}
+	return digest, nil
+}
+
+func dockerRegistryAndPath(image string) (registry, repoPath string) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0], parts[1]
+	}
+	if !strings.Contains(image, "/") {
+		return "registry-1.docker.io", "library/" + image
+	}
+	return "registry-1.docker.io", image
+}
+
+func dockerRegistryToken(registry, repoPath string) (string, error) {
+	if registry != "registry-1.docker.io" {
+		return "", nil
+	}
+	resp, err := http.Get(fmt.Sprintf("https://auth.docker.io/token?service=registry.docker.io&scope=repository:%s:pull", repoPath))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	return body.Token, nil
+}
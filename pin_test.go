@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseUsesRef(t *testing.T) {
+	testCases := []struct {
+		uses string
+		want usesRef
+		ok   bool
+	}{
+		{"actions/checkout@v4", usesRef{Kind: "action", Owner: "actions", Repo: "checkout", Ref: "v4"}, true},
+		{"actions/checkout@8f4b7f84864484a7bf31766abe9204da3cbe65b3", usesRef{Kind: "action", Owner: "actions", Repo: "checkout", Ref: "8f4b7f84864484a7bf31766abe9204da3cbe65b3"}, true},
+		{"./local/action", usesRef{Kind: "local"}, true},
+		{"docker://postgres:14", usesRef{Kind: "docker", Image: "postgres", Ref: "14"}, true},
+		{"not-a-valid-ref", usesRef{}, false},
+	}
+
+	for _, tc := range testCases {
+		got, ok := parseUsesRef(tc.uses)
+		if ok != tc.ok {
+			t.Errorf("parseUsesRef(%q) ok = %v, want %v", tc.uses, ok, tc.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseUsesRef(%q) = %+v, want %+v", tc.uses, got, tc.want)
+		}
+	}
+}
+
+func TestIsPinned(t *testing.T) {
+	if !isPinned(usesRef{Kind: "action", Ref: "8f4b7f84864484a7bf31766abe9204da3cbe65b3"}) {
+		t.Error("expected a 40-char SHA to be pinned")
+	}
+	if isPinned(usesRef{Kind: "action", Ref: "v4"}) {
+		t.Error("expected a tag to not be pinned")
+	}
+	if !isPinned(usesRef{Kind: "docker", Ref: "sha256:" + strings.Repeat("a", 64)}) {
+		t.Error("expected a digest to be pinned")
+	}
+	if isPinned(usesRef{Kind: "docker", Ref: "14"}) {
+		t.Error("expected a tag to not be pinned")
+	}
+	if !isPinned(usesRef{Kind: "local"}) {
+		t.Error("expected local refs to be treated as pinned (skipped)")
+	}
+}
+
+func TestDockerRegistryAndPath(t *testing.T) {
+	testCases := []struct {
+		image        string
+		wantRegistry string
+		wantPath     string
+	}{
+		{"postgres", "registry-1.docker.io", "library/postgres"},
+		{"bitnami/postgres", "registry-1.docker.io", "bitnami/postgres"},
+		{"ghcr.io/owner/image", "ghcr.io", "owner/image"},
+		{"localhost:5000/image", "localhost:5000", "image"},
+	}
+
+	for _, tc := range testCases {
+		registry, path := dockerRegistryAndPath(tc.image)
+		if registry != tc.wantRegistry || path != tc.wantPath {
+			t.Errorf("dockerRegistryAndPath(%q) = (%q, %q), want (%q, %q)", tc.image, registry, path, tc.wantRegistry, tc.wantPath)
+		}
+	}
+}
+
+func TestUnpinRef(t *testing.T) {
+	value, comment, err := unpinRef(usesRef{Kind: "action", Owner: "actions", Repo: "checkout", Ref: strings.Repeat("a", 40)}, "# v4")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "actions/checkout@v4" || comment != "" {
+		t.Errorf("expected actions/checkout@v4 with no comment, got %q %q", value, comment)
+	}
+
+	value, _, err = unpinRef(usesRef{Kind: "action", Ref: "v4"}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != "" {
+		t.Errorf("expected unpinned ref to be left alone, got %q", value)
+	}
+}
+
+func TestRewriteUsesRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ci.yml")
+	original := `name: CI
+on: push
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v4
+      - run: go build ./...
+`
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+
+	pinned := strings.Repeat("a", 40)
+	err := rewriteUses(path, func(ref usesRef, comment string) (string, string, error) {
+		if ref.Kind != "action" {
+			return "", "", nil
+		}
+		return ref.Owner + "/" + ref.Repo + "@" + pinned, "# " + ref.Ref, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read rewritten file: %v", err)
+	}
+	if !strings.Contains(string(data), "actions/checkout@"+pinned+" # v4") {
+		t.Errorf("expected pinned uses with trailing comment, got:\n%s", data)
+	}
+
+	err = rewriteUses(path, unpinRef)
+	if err != nil {
+		t.Fatalf("unexpected error unpinning: %v", err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read unpinned file: %v", err)
+	}
+	if !strings.Contains(string(data), "actions/checkout@v4") {
+		t.Errorf("expected ref restored to actions/checkout@v4, got:\n%s", data)
+	}
+}
@@ -0,0 +1,87 @@
+// Package pipeline defines a provider-agnostic intermediate
+// representation for CI pipelines: a Pipeline of Stages, each made up
+// of Steps, with a Trigger describing when it runs and Publish entries
+// describing what happens to its artifacts afterwards. Provider
+// packages (bitrise, codemagic, ...) lower their own config structs
+// into this IR via a ToPipeline method, so callers that only care about
+// "what does this pipeline do" can work against one model instead of
+// one per provider.
+package pipeline
+
+// Pipeline is the normalized form of one provider config's workflow (or
+// pipeline/stage group), expressed as a dependency-ordered list of
+// Stages.
+type Pipeline struct {
+	Stages  []Stage
+	Trigger Trigger
+	Publish []Publish
+}
+
+// Stage is a named group of Steps. RunIf and DependsOn carry the
+// provider's native conditional-run expression and stage dependencies
+// as opaque strings, since each provider's syntax differs and the IR
+// doesn't attempt to normalize expression languages.
+type Stage struct {
+	Name      string
+	Steps     []Step
+	RunIf     string
+	DependsOn []string
+}
+
+// Step is one unit of work within a Stage: either a shell Script (a
+// Codemagic script, a Bitrise `script@1` step) or a reference to a
+// provider-native step/action identified by ID, with arbitrary
+// provider-specific configuration threaded through Env.
+type Step struct {
+	ID            string
+	Name          string
+	Script        string
+	Env           map[string]string
+	IgnoreFailure bool
+	Artifacts     []string
+}
+
+// Trigger describes when a Pipeline runs, normalized from whatever
+// event/branch/tag matching scheme the source provider uses.
+type Trigger struct {
+	Events         []string
+	BranchInclude  []string
+	BranchExclude  []string
+	TagInclude     []string
+	TagExclude     []string
+	PullRequest    bool
+}
+
+// Publish is one post-run publishing step, e.g. a Codemagic
+// `publishing.slack` block or a Bitrise deploy step. Kind identifies
+// the publishing target ("slack", "email", "github", ...) and Params
+// carries its provider-specific settings verbatim.
+type Publish struct {
+	Kind   string
+	Params map[string]interface{}
+}
+
+// ConcurrencyScope is what a ConcurrencyPolicy's Group is scoped to:
+// runs only race each other for cancellation within the same Scope.
+type ConcurrencyScope string
+
+const (
+	ScopeBranch ConcurrencyScope = "branch"
+	ScopePR     ConcurrencyScope = "pr"
+	ScopeTag    ConcurrencyScope = "tag"
+	ScopeGlobal ConcurrencyScope = "global"
+)
+
+// ConcurrencyPolicy is the effective auto-cancel behavior for one
+// workflow, normalized from whichever provider-native mechanism
+// expresses it (Codemagic's `cancel_previous_builds`, a Bitrise step
+// that cancels in-progress runs, ...).
+type ConcurrencyPolicy struct {
+	// CancelInProgress reports whether a new run supersedes and
+	// cancels an already-running one in the same Group/Scope.
+	CancelInProgress bool
+	// Group identifies the set of runs that race for cancellation,
+	// e.g. the workflow name.
+	Group string
+	Scope ConcurrencyScope
+}
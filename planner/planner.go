@@ -0,0 +1,767 @@
+// Package planner turns one or more GitHub Actions workflow files into an
+// executable Plan: it resolves which workflows fire for a given event,
+// expands each job's strategy.matrix into concrete instances, and orders
+// jobs into dependency-respecting batches from their `needs`.
+package planner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tuist/notary/github"
+)
+
+// EventPayload is the decoded --event-file JSON payload, exposed to
+// conditions as github.event.*.
+type EventPayload map[string]interface{}
+
+// JobInstance is one concrete instantiation of a workflow job: a single
+// matrix combination (or the job itself, if it has no matrix).
+type JobInstance struct {
+	WorkflowName string
+	WorkflowFile string
+	JobID        string
+	Name         string
+	Job          github.Job
+	Matrix       map[string]interface{}
+	Needs        []string
+}
+
+// JobGroup is every JobInstance produced by expanding a single job's
+// strategy.matrix, along with the strategy settings that govern how its
+// instances run.
+type JobGroup struct {
+	JobID       string
+	Instances   []*JobInstance
+	MaxParallel int
+	FailFast    bool
+}
+
+// Plan is a dependency-ordered workflow run: Batches[i] can only start
+// once every JobGroup in Batches[0:i] has finished.
+type Plan struct {
+	Batches [][]*JobGroup
+}
+
+// PlanEvent globs workflowsGlob for workflow files, parses each, and
+// builds a Plan from the jobs of every workflow whose `on:` matches
+// event. workflowsGlob may be a directory (scanned for *.yml/*.yaml), a
+// glob pattern, or a single workflow file.
+func PlanEvent(workflowsGlob, event string, payload EventPayload) (*Plan, error) {
+	files, err := WorkflowFiles(workflowsGlob)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no workflow files found in %s", workflowsGlob)
+	}
+
+	groups := map[string]*JobGroup{}
+	needs := map[string][]string{}
+	var order []string
+
+	for _, file := range files {
+		workflow, err := github.ParseWorkflow(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if !matchesEvent(workflow.On, event) {
+			continue
+		}
+
+		for jobID, job := range workflow.Jobs {
+			if _, exists := groups[jobID]; exists {
+				return nil, fmt.Errorf("job %q is defined in more than one matched workflow", jobID)
+			}
+
+			jobNeeds := parseNeeds(job.Needs)
+			needs[jobID] = jobNeeds
+			order = append(order, jobID)
+
+			matrices, err := expandMatrix(job.Strategy)
+			if err != nil {
+				return nil, fmt.Errorf("job %q: %w", jobID, err)
+			}
+
+			group := &JobGroup{JobID: jobID, FailFast: true}
+			if job.Strategy != nil {
+				group.MaxParallel = job.Strategy.MaxParallel
+				if job.Strategy.FailFast != nil {
+					group.FailFast = *job.Strategy.FailFast
+				}
+			}
+
+			name := job.Name
+			if name == "" {
+				name = jobID
+			}
+
+			for _, matrix := range matrices {
+				instance := &JobInstance{
+					WorkflowName: workflow.Name,
+					WorkflowFile: file,
+					JobID:        jobID,
+					Name:         substituteMatrix(name, matrix),
+					Job:          substituteJob(job, matrix),
+					Matrix:       matrix,
+					Needs:        jobNeeds,
+				}
+				group.Instances = append(group.Instances, instance)
+			}
+
+			groups[jobID] = group
+		}
+	}
+
+	if len(groups) == 0 {
+		return &Plan{}, nil
+	}
+
+	sort.Strings(order)
+	batches, err := batchByNeeds(order, needs)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	for _, batch := range batches {
+		var jobGroups []*JobGroup
+		for _, jobID := range batch {
+			jobGroups = append(jobGroups, groups[jobID])
+		}
+		plan.Batches = append(plan.Batches, jobGroups)
+	}
+	return plan, nil
+}
+
+// WorkflowFiles resolves path to the workflow files it refers to: a
+// single file is returned as-is, and a directory is scanned for
+// *.yml/*.yaml (sorted, so callers get a deterministic order).
+func WorkflowFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	dir := path
+	if err != nil {
+		dir = path
+	}
+
+	var files []string
+	for _, ext := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, ext))
+		if err != nil {
+			return nil, fmt.Errorf("invalid workflow glob %s: %w", dir, err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchesEvent reports whether a workflow's `on:` value fires for event.
+// `on` is parsed from YAML and may be a bare string, a list of strings, or
+// a map keyed by event name (the map form used for `push: {branches: …}`).
+func matchesEvent(on interface{}, event string) bool {
+	switch v := on.(type) {
+	case string:
+		return v == event
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok && s == event {
+				return true
+			}
+		}
+		return false
+	case map[string]interface{}:
+		_, ok := v[event]
+		return ok
+	default:
+		return false
+	}
+}
+
+// parseNeeds normalizes a job's `needs:` field, which YAML may decode as
+// nil, a bare string, or a list of strings.
+func parseNeeds(needs interface{}) []string {
+	switch v := needs.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// batchByNeeds topologically sorts jobIDs into levels via Kahn's
+// algorithm: every job in a level depends only on jobs in earlier
+// levels, so levels can run one after another while jobs within a level
+// run concurrently. It errors on cycles and on needs that reference a
+// job that isn't part of the plan.
+func batchByNeeds(jobIDs []string, needs map[string][]string) ([][]string, error) {
+	known := map[string]bool{}
+	for _, id := range jobIDs {
+		known[id] = true
+	}
+	for job, deps := range needs {
+		for _, dep := range deps {
+			if !known[dep] {
+				return nil, fmt.Errorf("job %q needs undefined job %q", job, dep)
+			}
+		}
+	}
+
+	remaining := map[string][]string{}
+	for _, id := range jobIDs {
+		remaining[id] = append([]string{}, needs[id]...)
+	}
+
+	var batches [][]string
+	for len(remaining) > 0 {
+		var ready []string
+		for id, deps := range remaining {
+			if len(deps) == 0 {
+				ready = append(ready, id)
+			}
+		}
+		if len(ready) == 0 {
+			var stuck []string
+			for id := range remaining {
+				stuck = append(stuck, id)
+			}
+			sort.Strings(stuck)
+			return nil, fmt.Errorf("cycle detected in job needs graph: %v", stuck)
+		}
+		sort.Strings(ready)
+		batches = append(batches, ready)
+
+		for _, id := range ready {
+			delete(remaining, id)
+		}
+		for id, deps := range remaining {
+			kept := deps[:0]
+			for _, dep := range deps {
+				if !contains(ready, dep) {
+					kept = append(kept, dep)
+				}
+			}
+			remaining[id] = kept
+		}
+	}
+	return batches, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// expandMatrix returns the cartesian product of a strategy's matrix axes,
+// applying `include` (extra combinations, or extra keys merged onto
+// matching combinations) and `exclude` (combinations to drop) the way
+// GitHub Actions documents them. A nil strategy or empty matrix yields a
+// single, empty combination so the job still runs once.
+func expandMatrix(strategy *github.Strategy) ([]map[string]interface{}, error) {
+	if strategy == nil || len(strategy.Matrix) == 0 {
+		return []map[string]interface{}{{}}, nil
+	}
+
+	axes := map[string][]interface{}{}
+	var includes []interface{}
+	var excludes []interface{}
+	var keys []string
+
+	for key, value := range strategy.Matrix {
+		switch key {
+		case "include":
+			if list, ok := value.([]interface{}); ok {
+				includes = list
+			}
+		case "exclude":
+			if list, ok := value.([]interface{}); ok {
+				excludes = list
+			}
+		default:
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("matrix axis %q must be a list", key)
+			}
+			axes[key] = list
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	combos := []map[string]interface{}{{}}
+	for _, key := range keys {
+		var next []map[string]interface{}
+		for _, combo := range combos {
+			for _, value := range axes[key] {
+				merged := cloneMatrix(combo)
+				merged[key] = value
+				next = append(next, merged)
+			}
+		}
+		combos = next
+	}
+
+	for _, raw := range excludes {
+		filter, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kept := combos[:0]
+		for _, combo := range combos {
+			if !matrixMatches(combo, filter) {
+				kept = append(kept, combo)
+			}
+		}
+		combos = kept
+	}
+
+	for _, raw := range includes {
+		extra, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		merged := false
+		for _, combo := range combos {
+			if matrixSubset(extra, combo) {
+				for k, v := range extra {
+					combo[k] = v
+				}
+				merged = true
+			}
+		}
+		if !merged {
+			combos = append(combos, cloneMatrix(extra))
+		}
+	}
+
+	if len(combos) == 0 {
+		return nil, fmt.Errorf("matrix expansion produced no combinations")
+	}
+	return combos, nil
+}
+
+func cloneMatrix(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// matrixMatches reports whether combo contains every key/value in filter.
+func matrixMatches(combo, filter map[string]interface{}) bool {
+	for k, v := range filter {
+		if fmt.Sprintf("%v", combo[k]) != fmt.Sprintf("%v", v) {
+			return false
+		}
+	}
+	return true
+}
+
+// matrixSubset reports whether the axis keys extra shares with combo all
+// agree, i.e. extra should be merged onto combo rather than added as a
+// new combination.
+func matrixSubset(extra, combo map[string]interface{}) bool {
+	shared := false
+	for k, v := range extra {
+		if cv, ok := combo[k]; ok {
+			shared = true
+			if fmt.Sprintf("%v", cv) != fmt.Sprintf("%v", v) {
+				return false
+			}
+		}
+	}
+	return shared
+}
+
+var matrixExprRe = regexp.MustCompile(`\$\{\{\s*matrix\.([\w-]+)\s*\}\}`)
+
+func substituteMatrix(input string, matrix map[string]interface{}) string {
+	return matrixExprRe.ReplaceAllStringFunc(input, func(expr string) string {
+		key := matrixExprRe.FindStringSubmatch(expr)[1]
+		if v, ok := matrix[key]; ok {
+			return fmt.Sprintf("%v", v)
+		}
+		return expr
+	})
+}
+
+// substituteJob returns a copy of job with every `${{ matrix.* }}`
+// reference in its steps, env, and runs-on resolved against matrix.
+func substituteJob(job github.Job, matrix map[string]interface{}) github.Job {
+	if len(matrix) == 0 {
+		return job
+	}
+
+	out := job
+	if s, ok := job.RunsOn.(string); ok {
+		out.RunsOn = substituteMatrix(s, matrix)
+	}
+
+	out.Env = substituteEnv(job.Env, matrix)
+
+	out.Steps = make([]github.Step, len(job.Steps))
+	for i, step := range job.Steps {
+		step.Name = substituteMatrix(step.Name, matrix)
+		step.Run = substituteMatrix(step.Run, matrix)
+		step.Uses = substituteMatrix(step.Uses, matrix)
+		step.Env = substituteEnv(step.Env, matrix)
+		if step.With != nil {
+			with := make(map[string]interface{}, len(step.With))
+			for k, v := range step.With {
+				if s, ok := v.(string); ok {
+					with[k] = substituteMatrix(s, matrix)
+				} else {
+					with[k] = v
+				}
+			}
+			step.With = with
+		}
+		out.Steps[i] = step
+	}
+	return out
+}
+
+func substituteEnv(env map[string]string, matrix map[string]interface{}) map[string]string {
+	if env == nil {
+		return nil
+	}
+	out := make(map[string]string, len(env))
+	for k, v := range env {
+		out[k] = substituteMatrix(v, matrix)
+	}
+	return out
+}
+
+// EvalContext supplies the values a minimal `${{ }}` condition can
+// reference: env and matrix lookups, the triggering event name, and the
+// success()/failure()/always() outcome of whatever this condition gates.
+type EvalContext struct {
+	Env       map[string]string
+	Matrix    map[string]interface{}
+	EventName string
+	Failed    bool
+}
+
+var conditionRe = regexp.MustCompile(`^\$\{\{(.*)\}\}$`)
+
+// EvalCondition evaluates the minimal `if:` expression subset this
+// planner supports: success()/failure()/always(), and `==`/`!=` or
+// `&&`/`||` comparisons over env.*, matrix.*, and github.event_name. An
+// empty expr defaults to success().
+func EvalCondition(expr string, ctx EvalContext) (bool, error) {
+	expr = trimSpace(expr)
+	if expr == "" {
+		return !ctx.Failed, nil
+	}
+	if m := conditionRe.FindStringSubmatch(expr); m != nil {
+		expr = trimSpace(m[1])
+	}
+
+	if or := splitTop(expr, "||"); len(or) > 1 {
+		for _, part := range or {
+			ok, err := EvalCondition(part, ctx)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	if and := splitTop(expr, "&&"); len(and) > 1 {
+		for _, part := range and {
+			ok, err := EvalCondition(part, ctx)
+			if err != nil {
+				return false, err
+			}
+			if !ok {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	switch expr {
+	case "success()":
+		return !ctx.Failed, nil
+	case "failure()":
+		return ctx.Failed, nil
+	case "always()":
+		return true, nil
+	}
+
+	for _, op := range []string{"==", "!="} {
+		if parts := splitTop(expr, op); len(parts) == 2 {
+			left := resolveOperand(trimSpace(parts[0]), ctx)
+			right := resolveOperand(trimSpace(parts[1]), ctx)
+			if op == "==" {
+				return left == right, nil
+			}
+			return left != right, nil
+		}
+	}
+
+	return false, fmt.Errorf("unsupported condition: %q", expr)
+}
+
+func resolveOperand(s string, ctx EvalContext) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	switch {
+	case s == "github.event_name":
+		return ctx.EventName
+	case hasPrefix(s, "env."):
+		return ctx.Env[s[len("env."):]]
+	case hasPrefix(s, "matrix."):
+		if v, ok := ctx.Matrix[s[len("matrix."):]]; ok {
+			return fmt.Sprintf("%v", v)
+		}
+		return ""
+	default:
+		return s
+	}
+}
+
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+}
+
+func trimSpace(s string) string {
+	start, end := 0, len(s)
+	for start < end && isSpace(s[start]) {
+		start++
+	}
+	for end > start && isSpace(s[end-1]) {
+		end--
+	}
+	return s[start:end]
+}
+
+func isSpace(b byte) bool { return b == ' ' || b == '\t' || b == '\n' || b == '\r' }
+
+// splitTop splits expr on op only at paren-depth zero, so `a == 'x && y'`
+// isn't torn apart by the quoted `&&`.
+func splitTop(expr, op string) []string {
+	var parts []string
+	depth := 0
+	inQuote := byte(0)
+	last := 0
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case inQuote != 0:
+			if c == inQuote {
+				inQuote = 0
+			}
+		case c == '\'' || c == '"':
+			inQuote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && i+len(op) <= len(expr) && expr[i:i+len(op)] == op:
+			parts = append(parts, expr[last:i])
+			last = i + len(op)
+			i = last - 1
+		}
+	}
+	parts = append(parts, expr[last:])
+	return parts
+}
+
+// Executor runs the steps of a job instance on some backend.
+// Implementations live outside planner (see the runtime package) so
+// planner stays backend-agnostic. PrepareJob/CleanupJob bracket an
+// instance's whole step loop, which lets a backend start and tear down
+// per-job resources such as containers and services. stdout/stderr are
+// where the step's output should go; they are never nil.
+type Executor interface {
+	PrepareJob(ctx context.Context, instance *JobInstance) error
+	RunStep(ctx context.Context, instance *JobInstance, step github.Step, stdout, stderr io.Writer) error
+	CleanupJob(ctx context.Context, instance *JobInstance) error
+}
+
+// Reporter receives structured events about a Plan's execution, e.g. to
+// drive `notary run --log-format json`'s JSON event stream. A nil
+// Reporter in ExecuteOptions means Execute reports nothing.
+type Reporter interface {
+	JobStarted(instance *JobInstance)
+	StepStarted(instance *JobInstance, index int, step github.Step)
+	StepFinished(instance *JobInstance, index int, step github.Step, err error, duration time.Duration)
+	JobFinished(instance *JobInstance, err error)
+}
+
+// ExecuteOptions controls how a Plan is run. EventName feeds EvalContext
+// for `if:` conditions. Stdout/Stderr default to os.Stdout/os.Stderr
+// when nil; Reporter, if set, is notified of every state transition.
+type ExecuteOptions struct {
+	EventName string
+	Stdout    io.Writer
+	Stderr    io.Writer
+	Reporter  Reporter
+}
+
+// Execute runs every batch in order; within a batch, job groups run
+// concurrently, and each group's instances run concurrently up to
+// MaxParallel (0 means unbounded). If a group's FailFast is true (the
+// default) and one instance fails, its sibling instances are not
+// started; a failing group aborts the whole Execute call after its
+// batch finishes.
+func (p *Plan) Execute(ex Executor, opts ExecuteOptions) error {
+	if opts.Stdout == nil {
+		opts.Stdout = os.Stdout
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = os.Stderr
+	}
+
+	for _, batch := range p.Batches {
+		var wg sync.WaitGroup
+		errs := make([]error, len(batch))
+
+		for i, group := range batch {
+			wg.Add(1)
+			go func(i int, group *JobGroup) {
+				defer wg.Done()
+				errs[i] = runGroup(ex, group, opts)
+			}(i, group)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func runGroup(ex Executor, group *JobGroup, opts ExecuteOptions) error {
+	limit := group.MaxParallel
+	if limit <= 0 || limit > len(group.Instances) {
+		limit = len(group.Instances)
+	}
+	sem := make(chan struct{}, limit)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(group.Instances))
+	var aborted sync.Map
+
+	for i, instance := range group.Instances {
+		if _, skip := aborted.Load(true); skip && group.FailFast {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, instance *JobInstance) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = runInstance(ex, instance, opts)
+			if errs[i] != nil && group.FailFast {
+				aborted.Store(true, true)
+			}
+		}(i, instance)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runInstance(ex Executor, instance *JobInstance, opts ExecuteOptions) error {
+	ctx := context.Background()
+	evalCtx := EvalContext{Env: instance.Job.Env, Matrix: instance.Matrix, EventName: opts.EventName}
+
+	ok, err := EvalCondition(instance.Job.If, evalCtx)
+	if err != nil {
+		return fmt.Errorf("job %q: %w", instance.Name, err)
+	}
+	if !ok {
+		return nil
+	}
+
+	if opts.Reporter != nil {
+		opts.Reporter.JobStarted(instance)
+	}
+
+	if err := ex.PrepareJob(ctx, instance); err != nil {
+		err = fmt.Errorf("job %q: %w", instance.Name, err)
+		reportJobFinished(opts.Reporter, instance, err)
+		return err
+	}
+	defer ex.CleanupJob(ctx, instance)
+
+	failed := false
+	var jobErr error
+	for i, step := range instance.Job.Steps {
+		stepCtx := evalCtx
+		stepCtx.Failed = failed
+		ok, err := EvalCondition(step.If, stepCtx)
+		if err != nil {
+			jobErr = fmt.Errorf("job %q: %w", instance.Name, err)
+			break
+		}
+		if !ok {
+			continue
+		}
+
+		if opts.Reporter != nil {
+			opts.Reporter.StepStarted(instance, i, step)
+		}
+		start := time.Now()
+		stepErr := ex.RunStep(ctx, instance, step, opts.Stdout, opts.Stderr)
+		if opts.Reporter != nil {
+			opts.Reporter.StepFinished(instance, i, step, stepErr, time.Since(start))
+		}
+
+		if stepErr != nil {
+			if step.ContinueOnError {
+				continue
+			}
+			failed = true
+			jobErr = fmt.Errorf("job %q: %w", instance.Name, stepErr)
+			break
+		}
+	}
+
+	reportJobFinished(opts.Reporter, instance, jobErr)
+	return jobErr
+}
+
+func reportJobFinished(reporter Reporter, instance *JobInstance, err error) {
+	if reporter != nil {
+		reporter.JobFinished(instance, err)
+	}
+}
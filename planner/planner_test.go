@@ -0,0 +1,336 @@
+package planner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tuist/notary/github"
+)
+
+func TestMatchesEvent(t *testing.T) {
+	testCases := []struct {
+		name  string
+		on    interface{}
+		event string
+		want  bool
+	}{
+		{"string match", "push", "push", true},
+		{"string mismatch", "push", "pull_request", false},
+		{"list match", []interface{}{"push", "pull_request"}, "pull_request", true},
+		{"list mismatch", []interface{}{"push"}, "workflow_dispatch", false},
+		{"map match", map[string]interface{}{"push": map[string]interface{}{"branches": []interface{}{"main"}}}, "push", true},
+		{"map mismatch", map[string]interface{}{"pull_request": nil}, "push", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchesEvent(tc.on, tc.event); got != tc.want {
+				t.Errorf("matchesEvent(%v, %q) = %v, want %v", tc.on, tc.event, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseNeeds(t *testing.T) {
+	if got := parseNeeds(nil); got != nil {
+		t.Errorf("expected nil needs, got %v", got)
+	}
+	if got := parseNeeds("build"); len(got) != 1 || got[0] != "build" {
+		t.Errorf("expected [build], got %v", got)
+	}
+	if got := parseNeeds([]interface{}{"build", "test"}); len(got) != 2 {
+		t.Errorf("expected 2 needs, got %v", got)
+	}
+}
+
+func TestBatchByNeeds(t *testing.T) {
+	needs := map[string][]string{
+		"build":  nil,
+		"test":   {"build"},
+		"deploy": {"build", "test"},
+	}
+	batches, err := batchByNeeds([]string{"build", "test", "deploy"}, needs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batches) != 3 {
+		t.Fatalf("expected 3 batches, got %d: %v", len(batches), batches)
+	}
+	if batches[0][0] != "build" || batches[1][0] != "test" || batches[2][0] != "deploy" {
+		t.Errorf("unexpected batch order: %v", batches)
+	}
+}
+
+func TestBatchByNeedsCycle(t *testing.T) {
+	needs := map[string][]string{
+		"a": {"b"},
+		"b": {"a"},
+	}
+	if _, err := batchByNeeds([]string{"a", "b"}, needs); err == nil {
+		t.Error("expected cycle error, got none")
+	}
+}
+
+func TestBatchByNeedsMissingDep(t *testing.T) {
+	needs := map[string][]string{
+		"test": {"build"},
+	}
+	if _, err := batchByNeeds([]string{"test"}, needs); err == nil {
+		t.Error("expected missing dependency error, got none")
+	}
+}
+
+func TestExpandMatrix(t *testing.T) {
+	strategy := &github.Strategy{
+		Matrix: map[string]interface{}{
+			"os": []interface{}{"ubuntu-latest", "macos-latest"},
+			"go": []interface{}{"1.20", "1.21"},
+			"exclude": []interface{}{
+				map[string]interface{}{"os": "macos-latest", "go": "1.20"},
+			},
+			"include": []interface{}{
+				map[string]interface{}{"os": "macos-latest", "go": "1.20", "experimental": true},
+			},
+		},
+	}
+
+	combos, err := expandMatrix(strategy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(combos) != 4 {
+		t.Fatalf("expected 4 combinations, got %d: %v", len(combos), combos)
+	}
+
+	found := false
+	for _, combo := range combos {
+		if combo["os"] == "macos-latest" && combo["go"] == "1.20" {
+			found = true
+			if combo["experimental"] != true {
+				t.Errorf("expected excluded+included combo to carry experimental=true, got %v", combo)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected the include entry to re-add the excluded combination")
+	}
+}
+
+func TestExpandMatrixEmpty(t *testing.T) {
+	combos, err := expandMatrix(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(combos) != 1 || len(combos[0]) != 0 {
+		t.Errorf("expected a single empty combination, got %v", combos)
+	}
+}
+
+func TestSubstituteMatrix(t *testing.T) {
+	matrix := map[string]interface{}{"go": "1.21"}
+	got := substituteMatrix("setup-go ${{ matrix.go }}", matrix)
+	if got != "setup-go 1.21" {
+		t.Errorf("expected substitution, got %q", got)
+	}
+}
+
+func TestEvalCondition(t *testing.T) {
+	ctx := EvalContext{
+		Env:       map[string]string{"DEPLOY": "true"},
+		EventName: "push",
+	}
+
+	testCases := []struct {
+		name string
+		expr string
+		ctx  EvalContext
+		want bool
+	}{
+		{"empty defaults to success", "", ctx, true},
+		{"explicit success", "success()", ctx, true},
+		{"failure when not failed", "failure()", ctx, false},
+		{"always runs", "always()", EvalContext{Failed: true}, true},
+		{"env equality", "${{ env.DEPLOY == 'true' }}", ctx, true},
+		{"event mismatch", "github.event_name == 'pull_request'", ctx, false},
+		{"and", "success() && env.DEPLOY == 'true'", ctx, true},
+		{"or", "failure() || env.DEPLOY == 'true'", ctx, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := EvalCondition(tc.expr, tc.ctx)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("EvalCondition(%q) = %v, want %v", tc.expr, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPlanEvent(t *testing.T) {
+	dir := t.TempDir()
+	workflow := `
+name: CI
+on: [push]
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo building
+  test:
+    runs-on: ubuntu-latest
+    needs: build
+    strategy:
+      matrix:
+        go: ["1.20", "1.21"]
+    steps:
+      - run: go test ./... # ${{ matrix.go }}
+`
+	if err := os.WriteFile(filepath.Join(dir, "ci.yml"), []byte(workflow), 0644); err != nil {
+		t.Fatalf("failed to write workflow: %v", err)
+	}
+
+	plan, err := PlanEvent(dir, "push", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Batches) != 2 {
+		t.Fatalf("expected 2 batches, got %d", len(plan.Batches))
+	}
+	if plan.Batches[0][0].JobID != "build" {
+		t.Errorf("expected build job first, got %q", plan.Batches[0][0].JobID)
+	}
+	if got := len(plan.Batches[1][0].Instances); got != 2 {
+		t.Errorf("expected 2 matrix instances for test job, got %d", got)
+	}
+}
+
+func TestPlanEventNoMatch(t *testing.T) {
+	dir := t.TempDir()
+	workflow := `
+name: CI
+on: workflow_dispatch
+jobs:
+  build:
+    runs-on: ubuntu-latest
+    steps:
+      - run: echo hi
+`
+	if err := os.WriteFile(filepath.Join(dir, "ci.yml"), []byte(workflow), 0644); err != nil {
+		t.Fatalf("failed to write workflow: %v", err)
+	}
+
+	plan, err := PlanEvent(dir, "push", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Batches) != 0 {
+		t.Errorf("expected no batches for unmatched event, got %d", len(plan.Batches))
+	}
+}
+
+type fakeExecutor struct {
+	fail bool
+}
+
+func (f *fakeExecutor) PrepareJob(ctx context.Context, instance *JobInstance) error { return nil }
+func (f *fakeExecutor) CleanupJob(ctx context.Context, instance *JobInstance) error { return nil }
+func (f *fakeExecutor) RunStep(ctx context.Context, instance *JobInstance, step github.Step, stdout, stderr io.Writer) error {
+	fmt.Fprintf(stdout, "ran %s\n", step.Run)
+	if f.fail {
+		return fmt.Errorf("step failed")
+	}
+	return nil
+}
+
+type fakeReporter struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *fakeReporter) JobStarted(instance *JobInstance) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, "job_started:"+instance.JobID)
+}
+
+func (r *fakeReporter) StepStarted(instance *JobInstance, index int, step github.Step) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("step_started:%s:%d", instance.JobID, index))
+}
+
+func (r *fakeReporter) StepFinished(instance *JobInstance, index int, step github.Step, err error, duration time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("step_finished:%s:%d:%v", instance.JobID, index, err != nil))
+}
+
+func (r *fakeReporter) JobFinished(instance *JobInstance, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf("job_finished:%s:%v", instance.JobID, err != nil))
+}
+
+func TestExecuteReportsEventsAndStreamsOutput(t *testing.T) {
+	plan := &Plan{Batches: [][]*JobGroup{
+		{{
+			JobID: "build",
+			Instances: []*JobInstance{
+				{JobID: "build", Name: "build", Job: github.Job{Steps: []github.Step{{Run: "echo hi"}}}},
+			},
+		}},
+	}}
+
+	reporter := &fakeReporter{}
+	var stdout bytes.Buffer
+	err := plan.Execute(&fakeExecutor{}, ExecuteOptions{Reporter: reporter, Stdout: &stdout})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "ran echo hi") {
+		t.Errorf("expected step output to be streamed to the provided writer, got %q", stdout.String())
+	}
+
+	want := []string{"job_started:build", "step_started:build:0", "step_finished:build:0:false", "job_finished:build:false"}
+	if len(reporter.events) != len(want) {
+		t.Fatalf("expected %v, got %v", want, reporter.events)
+	}
+	for i, ev := range want {
+		if reporter.events[i] != ev {
+			t.Errorf("expected %v, got %v", want, reporter.events)
+			break
+		}
+	}
+}
+
+func TestExecuteReportsJobFailure(t *testing.T) {
+	plan := &Plan{Batches: [][]*JobGroup{
+		{{
+			JobID: "build",
+			Instances: []*JobInstance{
+				{JobID: "build", Name: "build", Job: github.Job{Steps: []github.Step{{Run: "exit 1"}}}},
+			},
+		}},
+	}}
+
+	reporter := &fakeReporter{}
+	err := plan.Execute(&fakeExecutor{fail: true}, ExecuteOptions{Reporter: reporter})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	last := reporter.events[len(reporter.events)-1]
+	if last != "job_finished:build:true" {
+		t.Errorf("expected job_finished:build:true, got %q", last)
+	}
+}
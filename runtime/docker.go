@@ -0,0 +1,233 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/tuist/notary/github"
+	"github.com/tuist/notary/planner"
+)
+
+// containerWorkdir is where DockerRuntime mounts the repository inside a
+// job's container, mirroring GitHub-hosted runners' /github/workspace.
+const containerWorkdir = "/github/workspace"
+
+// DockerRuntime runs a job's steps inside its `container:` image, with
+// every `services:` entry started on a shared user-defined bridge
+// network so steps can reach them by service name (e.g. postgres:5432).
+// Jobs without a container: block fall back to the host runtime.
+type DockerRuntime struct {
+	host *HostRuntime
+
+	mu       sync.Mutex
+	networks map[string]bool
+	services map[string][]string // network name -> service container names
+}
+
+func (d *DockerRuntime) Name() string { return "docker" }
+
+func (d *DockerRuntime) PrepareJob(ctx context.Context, instance *planner.JobInstance) error {
+	if instance.Job.Container == nil {
+		return d.host.PrepareJob(ctx, instance)
+	}
+
+	network := networkName(instance)
+	if err := d.runDocker(ctx, "network", "create", network); err != nil {
+		return fmt.Errorf("failed to create docker network %s: %w", network, err)
+	}
+	d.rememberNetwork(network)
+
+	container := instance.Job.Container
+	if err := d.login(ctx, container.Credentials); err != nil {
+		return err
+	}
+	if err := d.runDocker(ctx, "pull", container.Image); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", container.Image, err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	runArgs := []string{
+		"run", "-d",
+		"--name", containerName(instance),
+		"--network", network,
+		"-v", fmt.Sprintf("%s:%s", wd, containerWorkdir),
+		"-w", containerWorkdir,
+	}
+	for k, v := range container.Env {
+		runArgs = append(runArgs, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	runArgs = append(runArgs, container.Image, "tail", "-f", "/dev/null")
+	if err := d.runDocker(ctx, runArgs...); err != nil {
+		return fmt.Errorf("failed to start container %s: %w", container.Image, err)
+	}
+
+	for name, service := range instance.Job.Services {
+		if err := d.startService(ctx, network, name, service); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *DockerRuntime) startService(ctx context.Context, network, name string, service github.Service) error {
+	if err := d.login(ctx, service.Credentials); err != nil {
+		return err
+	}
+	if err := d.runDocker(ctx, "pull", service.Image); err != nil {
+		return fmt.Errorf("failed to pull service %s: %w", name, err)
+	}
+
+	runArgs := []string{
+		"run", "-d",
+		"--name", name,
+		"--network", network,
+		"--network-alias", name,
+	}
+	for k, v := range service.Env {
+		runArgs = append(runArgs, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	for _, port := range service.Ports {
+		runArgs = append(runArgs, "-p", port)
+	}
+	runArgs = append(runArgs, service.Image)
+	if err := d.runDocker(ctx, runArgs...); err != nil {
+		return fmt.Errorf("failed to start service %s: %w", name, err)
+	}
+
+	d.mu.Lock()
+	if d.services == nil {
+		d.services = map[string][]string{}
+	}
+	d.services[network] = append(d.services[network], name)
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *DockerRuntime) login(ctx context.Context, creds *github.Credentials) error {
+	if creds == nil {
+		return nil
+	}
+	cmd := loginCommand(ctx, creds)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker login: %w\n%s", err, out)
+	}
+	return nil
+}
+
+// loginCommand builds the `docker login` command for creds, piping the
+// password over stdin rather than passing it as a CLI argument, where it
+// would be readable by other local users via ps or /proc/<pid>/cmdline.
+func loginCommand(ctx context.Context, creds *github.Credentials) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "docker", "login", "-u", creds.Username, "--password-stdin")
+	cmd.Stdin = strings.NewReader(creds.Password)
+	return cmd
+}
+
+func (d *DockerRuntime) RunStep(ctx context.Context, instance *planner.JobInstance, step github.Step, stdout, stderr io.Writer) error {
+	if instance.Job.Container == nil {
+		return d.host.RunStep(ctx, instance, step, stdout, stderr)
+	}
+
+	label := step.Name
+	if label == "" {
+		label = step.Run
+	}
+	fmt.Fprintf(stdout, "\n📦 Job: %s (container: %s)\n  Step: %s\n", instance.Name, instance.Job.Container.Image, label)
+
+	if step.Run == "" {
+		if step.Uses != "" {
+			fmt.Fprintf(stdout, "    ⚠️  Action %s would run in CI environment\n", step.Uses)
+		}
+		return nil
+	}
+
+	shell := step.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+
+	execArgs := []string{"exec"}
+	for k, v := range step.Env {
+		execArgs = append(execArgs, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	workdir := containerWorkdir
+	if step.WorkingDirectory != "" {
+		workdir = step.WorkingDirectory
+	}
+	execArgs = append(execArgs, "-w", workdir, containerName(instance), shell, "-c", step.Run)
+
+	if err := d.runDockerStreamed(ctx, stdout, stderr, execArgs...); err != nil {
+		return fmt.Errorf("step '%s' failed: %w", label, err)
+	}
+	return nil
+}
+
+func (d *DockerRuntime) CleanupJob(ctx context.Context, instance *planner.JobInstance) error {
+	if instance.Job.Container == nil {
+		return d.host.CleanupJob(ctx, instance)
+	}
+
+	network := networkName(instance)
+	_ = d.runDocker(ctx, "rm", "-f", containerName(instance))
+	for _, service := range d.servicesFor(network) {
+		_ = d.runDocker(ctx, "rm", "-f", service)
+	}
+	if d.wasNetworkCreated(network) {
+		_ = d.runDocker(ctx, "network", "rm", network)
+	}
+	return nil
+}
+
+func (d *DockerRuntime) rememberNetwork(network string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.networks == nil {
+		d.networks = map[string]bool{}
+	}
+	d.networks[network] = true
+}
+
+// servicesFor returns a copy of the service container names started on
+// network, since startService (called concurrently from another job's
+// PrepareJob) writes to d.services under d.mu.
+func (d *DockerRuntime) servicesFor(network string) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	services := make([]string, len(d.services[network]))
+	copy(services, d.services[network])
+	return services
+}
+
+func (d *DockerRuntime) wasNetworkCreated(network string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.networks[network]
+}
+
+func (d *DockerRuntime) runDocker(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker %v: %w\n%s", args, err, out)
+	}
+	return nil
+}
+
+func (d *DockerRuntime) runDockerStreamed(ctx context.Context, stdout, stderr io.Writer, args ...string) error {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/tuist/notary/github"
+	"github.com/tuist/notary/planner"
+)
+
+// HostRuntime runs every step with "sh -c" on the machine notary is
+// invoked from. It's the original notary run behavior and the default
+// when a workflow has no container: block.
+type HostRuntime struct{}
+
+func (h *HostRuntime) Name() string { return "host" }
+
+func (h *HostRuntime) PrepareJob(ctx context.Context, instance *planner.JobInstance) error {
+	return nil
+}
+
+func (h *HostRuntime) CleanupJob(ctx context.Context, instance *planner.JobInstance) error {
+	return nil
+}
+
+func (h *HostRuntime) RunStep(ctx context.Context, instance *planner.JobInstance, step github.Step, stdout, stderr io.Writer) error {
+	label := step.Name
+	if label == "" {
+		label = step.Run
+	}
+	fmt.Fprintf(stdout, "\n📦 Job: %s\n  Step: %s\n", instance.Name, label)
+
+	if step.Run != "" {
+		if err := h.runCommand(ctx, step, stdout, stderr); err != nil {
+			return fmt.Errorf("step '%s' failed: %w", label, err)
+		}
+		return nil
+	}
+
+	if step.Uses != "" {
+		fmt.Fprintf(stdout, "    Using action: %s\n", step.Uses)
+		if step.Uses == "actions/checkout@v4" || step.Uses == "actions/checkout@v3" {
+			fmt.Fprintln(stdout, "    ✓ Repository already checked out (local)")
+		} else {
+			fmt.Fprintf(stdout, "    ⚠️  Action %s would run in CI environment\n", step.Uses)
+		}
+	}
+	return nil
+}
+
+func (h *HostRuntime) runCommand(ctx context.Context, step github.Step, stdout, stderr io.Writer) error {
+	shell := step.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+	cmd := exec.CommandContext(ctx, shell, "-c", step.Run)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Stdin = os.Stdin
+	if step.WorkingDirectory != "" {
+		cmd.Dir = step.WorkingDirectory
+	}
+
+	cmd.Env = os.Environ()
+	for k, v := range step.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
+	}
+
+	return cmd.Run()
+}
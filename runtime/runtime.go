@@ -0,0 +1,67 @@
+// Package runtime provides pluggable backends that execute a planned
+// job's steps: HostRuntime runs them directly on the machine notary is
+// invoked from, DockerRuntime runs them inside the job's container: and
+// services: when the workflow declares one.
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/tuist/notary/github"
+	"github.com/tuist/notary/planner"
+)
+
+// Runtime executes the steps of a planned job instance. It satisfies
+// planner.Executor so a Plan can run directly against it.
+type Runtime interface {
+	Name() string
+	PrepareJob(ctx context.Context, instance *planner.JobInstance) error
+	RunStep(ctx context.Context, instance *planner.JobInstance, step github.Step, stdout, stderr io.Writer) error
+	CleanupJob(ctx context.Context, instance *planner.JobInstance) error
+}
+
+// New resolves a runtime by name ("host" or "docker"). When name is
+// "docker" but the Docker daemon isn't reachable, it falls back to the
+// host runtime and returns ok=false so callers can warn.
+func New(name string) (rt Runtime, ok bool) {
+	switch name {
+	case "docker":
+		if dockerAvailable() {
+			return &DockerRuntime{host: &HostRuntime{}}, true
+		}
+		return &HostRuntime{}, false
+	default:
+		return &HostRuntime{}, true
+	}
+}
+
+func dockerAvailable() bool {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return false
+	}
+	return exec.Command("docker", "info").Run() == nil
+}
+
+func containerName(instance *planner.JobInstance) string {
+	return fmt.Sprintf("notary-%s-%s", sanitize(instance.JobID), sanitize(instance.Name))
+}
+
+func networkName(instance *planner.JobInstance) string {
+	return fmt.Sprintf("notary-%s", sanitize(instance.JobID))
+}
+
+func sanitize(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-' || r == '_':
+			out = append(out, r)
+		default:
+			out = append(out, '-')
+		}
+	}
+	return string(out)
+}
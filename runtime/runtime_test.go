@@ -0,0 +1,131 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tuist/notary/github"
+	"github.com/tuist/notary/planner"
+)
+
+func TestNewDefaultsToHost(t *testing.T) {
+	rt, ok := New("host")
+	if !ok {
+		t.Fatal("expected host runtime to always be ok")
+	}
+	if rt.Name() != "host" {
+		t.Errorf("expected host runtime, got %q", rt.Name())
+	}
+}
+
+func TestNewUnknownFallsBackToHost(t *testing.T) {
+	rt, ok := New("made-up")
+	if !ok {
+		t.Fatal("expected fallback to report ok")
+	}
+	if rt.Name() != "host" {
+		t.Errorf("expected host runtime for unknown name, got %q", rt.Name())
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	if got := sanitize("build (ubuntu-latest, 1.21)"); got != "build--ubuntu-latest--1-21-" {
+		t.Errorf("unexpected sanitized name: %q", got)
+	}
+}
+
+func TestHostRuntimeRunStep(t *testing.T) {
+	h := &HostRuntime{}
+	instance := &planner.JobInstance{Name: "build"}
+
+	if err := h.PrepareJob(context.Background(), instance); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var stdout, stderr bytes.Buffer
+	if err := h.RunStep(context.Background(), instance, github.Step{Run: "true"}, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error running step: %v", err)
+	}
+	if err := h.CleanupJob(context.Background(), instance); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHostRuntimeRunStepFailure(t *testing.T) {
+	h := &HostRuntime{}
+	instance := &planner.JobInstance{Name: "build"}
+
+	var stdout, stderr bytes.Buffer
+	if err := h.RunStep(context.Background(), instance, github.Step{Run: "exit 1"}, &stdout, &stderr); err == nil {
+		t.Error("expected an error from a failing step")
+	}
+}
+
+func TestLoginCommandDoesNotLeakPasswordInArgs(t *testing.T) {
+	creds := &github.Credentials{Username: "deploy", Password: "s3cr3t"}
+
+	cmd := loginCommand(context.Background(), creds)
+
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, creds.Password) {
+			t.Fatalf("expected password to never appear in command args, got %v", cmd.Args)
+		}
+	}
+
+	stdin, ok := cmd.Stdin.(io.Reader)
+	if !ok {
+		t.Fatal("expected password to be piped via stdin")
+	}
+	got, err := io.ReadAll(stdin)
+	if err != nil {
+		t.Fatalf("unexpected error reading stdin: %v", err)
+	}
+	if string(got) != creds.Password {
+		t.Errorf("expected stdin to carry the password, got %q", got)
+	}
+}
+
+func TestDockerRuntimeServicesForConcurrentWithStartService(t *testing.T) {
+	d := &DockerRuntime{}
+	network := "notary-net"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Mirrors startService's map write under d.mu, without
+			// shelling out to the docker binary.
+			d.mu.Lock()
+			if d.services == nil {
+				d.services = map[string][]string{}
+			}
+			d.services[network] = append(d.services[network], fmt.Sprintf("service-%d", i))
+			d.mu.Unlock()
+
+			_ = d.servicesFor(network)
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(d.servicesFor(network)); got != 50 {
+		t.Errorf("expected 50 remembered services, got %d", got)
+	}
+}
+
+func TestHostRuntimeRunStepStreamsOutput(t *testing.T) {
+	h := &HostRuntime{}
+	instance := &planner.JobInstance{Name: "build"}
+
+	var stdout, stderr bytes.Buffer
+	if err := h.RunStep(context.Background(), instance, github.Step{Run: "echo hi"}, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(stdout.Bytes(), []byte("hi")) {
+		t.Errorf("expected step stdout to be captured, got %q", stdout.String())
+	}
+}
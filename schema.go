@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tuist/notary/bitrise"
+	"github.com/tuist/notary/codemagic"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a JSON Schema for a CI provider's config format",
+	Long: `Schema prints the JSON Schema document for --format's config
+struct (bitrise or codemagic) to stdout, suitable for editor
+autocompletion and validation of bitrise.yml/codemagic.yaml files.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+
+		var schema []byte
+		switch format {
+		case "bitrise":
+			schema = bitrise.JSONSchema()
+		case "codemagic":
+			schema = codemagic.JSONSchema()
+		default:
+			return fmt.Errorf("unknown format %q: must be bitrise or codemagic", format)
+		}
+
+		fmt.Println(string(schema))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+
+	schemaCmd.Flags().String("format", "bitrise", "Config format to generate a schema for: bitrise or codemagic")
+}